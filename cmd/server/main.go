@@ -2,30 +2,82 @@ package main
 
 import (
 	"context"
+	"ipmanlk/bettercopelk/internal/cache"
 	"ipmanlk/bettercopelk/internal/handlers"
 	"ipmanlk/bettercopelk/internal/services"
 	"ipmanlk/bettercopelk/internal/sources"
-	"ipmanlk/bettercopelk/internal/sources/baiscopelk"
-	"ipmanlk/bettercopelk/internal/sources/cineru"
-	"ipmanlk/bettercopelk/internal/sources/piratelk"
-	"ipmanlk/bettercopelk/internal/sources/zoomlk"
+	"ipmanlk/bettercopelk/internal/sources/wordpress"
 	"ipmanlk/bettercopelk/internal/static"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strconv"
+	"syscall"
 	"time"
 )
 
+const (
+	healthCheckInterval        = 2 * time.Minute
+	definitionsPollInterval    = 10 * time.Second
+	defaultCacheDir            = "cache"
+	defaultSearchCacheTTL      = 10 * time.Minute
+	defaultSearchCacheSizeMB   = 16
+	defaultDownloadStale       = 6 * time.Hour
+	defaultDownloadCacheSizeMB = 50
+)
+
 func main() {
+	bgCtx, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+
 	sourceManager := sources.NewManager()
+	healthMonitor := sources.NewHealthMonitor(sourceManager, healthCheckInterval)
+
+	if path := os.Getenv("SOURCES_CONFIG"); path != "" {
+		reloader := wordpress.NewReloader(path, sourceManager)
+		if err := reloader.Reload(); err != nil {
+			log.Fatalf("Failed to load source definitions: %v", err)
+		}
 
-	sourceManager.RegisterSource(baiscopelk.New())
-	sourceManager.RegisterSource(cineru.New())
-	sourceManager.RegisterSource(piratelk.New())
-	sourceManager.RegisterSource(zoomlk.New())
+		// New sources are probed right away instead of at the next health check
+		go reloader.Watch(bgCtx, definitionsPollInterval, healthMonitor.CheckAll)
+		go reloadOnSignal(reloader, healthMonitor)
+	} else {
+		definitions, err := wordpress.DefaultDefinitions()
+		if err != nil {
+			log.Fatalf("Failed to load source definitions: %v", err)
+		}
+		for _, def := range definitions {
+			sourceManager.RegisterSource(wordpress.New(def))
+		}
+	}
 
-	subtitleService := services.NewSubtitleService(sourceManager)
+	go healthMonitor.Start(bgCtx)
+
+	backend := os.Getenv("CACHE_BACKEND")
+	cacheDir := os.Getenv("CACHE_DIR")
+	if cacheDir == "" {
+		cacheDir = defaultCacheDir
+	}
+
+	searchCache := openStore(backend, filepath.Join(cacheDir, "search"),
+		intFromEnv("SEARCH_CACHE_SIZE_MB", defaultSearchCacheSizeMB),
+		durationFromEnv("SEARCH_CACHE_TTL", defaultSearchCacheTTL))
+
+	downloadTTL := durationFromEnv("DOWNLOAD_CACHE_TTL", services.DefaultDownloadTTL)
+	downloads := openStore(backend, filepath.Join(cacheDir, "downloads"),
+		intFromEnv("DOWNLOAD_CACHE_SIZE_MB", defaultDownloadCacheSizeMB),
+		downloadTTL+durationFromEnv("DOWNLOAD_CACHE_STALE", defaultDownloadStale))
+
+	subtitleService := services.NewSubtitleService(sourceManager, healthMonitor, services.Options{
+		SourceTimeout: durationFromEnv("SOURCE_TIMEOUT", services.DefaultSourceTimeout),
+		SearchTimeout: durationFromEnv("SEARCH_TIMEOUT", services.DefaultSearchTimeout),
+		SearchCache:   searchCache,
+		Downloads:     downloads,
+		DownloadTTL:   downloadTTL,
+	})
 
 	subtitleHandler := handlers.NewSubtitleHandler(subtitleService)
 	mux := http.NewServeMux()
@@ -54,9 +106,75 @@ func main() {
 	<-c
 
 	log.Println("Shutting down server...")
+	stopBackground()
 	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
 	defer cancel()
 	srv.Shutdown(ctx)
+
+	for _, store := range []cache.Store{searchCache, downloads} {
+		if store == nil {
+			continue
+		}
+		if err := store.Close(); err != nil {
+			log.Printf("Failed to close cache: %v", err)
+		}
+	}
+}
+
+// durationFromEnv parses a duration such as "10s" from the environment
+func durationFromEnv(name string, fallback time.Duration) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		log.Fatalf("Invalid %s %q: %v", name, value, err)
+	}
+	return duration
+}
+
+// openStore opens a cache store, returning nil (no caching) when its size or max age is 0
+func openStore(backend, dir string, sizeMB int, maxAge time.Duration) cache.Store {
+	if sizeMB <= 0 || maxAge <= 0 {
+		return nil
+	}
+
+	store, err := cache.Open(backend, dir, int64(sizeMB)<<20, maxAge)
+	if err != nil {
+		log.Fatalf("Failed to open cache in %s: %v", dir, err)
+	}
+	return store
+}
+
+// intFromEnv parses a whole number from the environment
+func intFromEnv(name string, fallback int) int {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		log.Fatalf("Invalid %s %q: %v", name, value, err)
+	}
+	return number
+}
+
+// reloadOnSignal reloads source definitions on SIGHUP and re-probes the new sources
+func reloadOnSignal(reloader *wordpress.Reloader, healthMonitor *sources.HealthMonitor) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	for range hup {
+		if err := reloader.Reload(); err != nil {
+			log.Printf("Failed to reload source definitions: %v", err)
+			continue
+		}
+		log.Println("Reloaded source definitions")
+		healthMonitor.CheckAll()
+	}
 }
 
 func loggingMiddleware(next http.Handler) http.Handler {
@@ -72,7 +190,8 @@ func corsMiddleware(next http.Handler) http.Handler {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
 		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
-		w.Header().Set("Access-Control-Expose-Headers", "Content-Type, Cache-Control, Connection")
+		w.Header().Set("Access-Control-Expose-Headers", "Content-Type, Cache-Control, Connection, Content-Disposition, "+
+			"X-Detected-Encoding, X-Subtitle-Format, X-Subtitle-Cues, X-Subtitle-Span, X-Subtitle-Sinhala-Percent, X-Subtitle-Issues")
 
 		if r.Method == "OPTIONS" {
 			w.WriteHeader(http.StatusOK)
@@ -2,4 +2,7 @@ module ipmanlk/bettercopelk
 
 go 1.24.1
 
-require golang.org/x/net v0.41.0
+require (
+	github.com/nwaples/rardecode v1.1.3
+	golang.org/x/net v0.41.0
+)
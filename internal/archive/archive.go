@@ -0,0 +1,243 @@
+// Package archive opens the ZIP and RAR archives subtitle sites serve and reads the
+// subtitle files inside them.
+package archive
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/nwaples/rardecode"
+)
+
+const (
+	FormatZIP = "zip"
+	FormatRAR = "rar"
+)
+
+// MaxFileSize bounds the size of a single extracted file, subtitles are far smaller
+const MaxFileSize = 20 << 20
+
+// MaxTotalSize and MaxFiles bound what ExtractAll reads from one archive
+const (
+	MaxTotalSize = 50 << 20
+	MaxFiles     = 500
+)
+
+var (
+	ErrUnsupported  = errors.New("unsupported archive format")
+	ErrFileNotFound = errors.New("file not found in archive")
+	ErrFileTooLarge = errors.New("file in archive is too large")
+)
+
+// subtitleExtensions are the file types listed from archives
+var subtitleExtensions = map[string]bool{
+	".srt": true,
+	".ass": true,
+	".ssa": true,
+	".vtt": true,
+	".sub": true,
+}
+
+// File is a subtitle file inside an archive
+type File struct {
+	// Name is the path of the file inside the archive
+	Name string `json:"name"`
+	Size int64  `json:"size"`
+}
+
+// Detect returns the archive format of content from its signature, or "" when it is not
+// a supported archive
+func Detect(content []byte) string {
+	switch {
+	case bytes.HasPrefix(content, []byte("PK\x03\x04")), bytes.HasPrefix(content, []byte("PK\x05\x06")):
+		return FormatZIP
+	case bytes.HasPrefix(content, []byte("Rar!\x1a\x07")):
+		return FormatRAR
+	default:
+		return ""
+	}
+}
+
+// IsSubtitle reports whether name has a subtitle file extension
+func IsSubtitle(name string) bool {
+	return subtitleExtensions[strings.ToLower(path.Ext(name))]
+}
+
+// List returns the subtitle files in an archive sorted by name
+func List(content []byte) ([]File, error) {
+	var files []File
+	err := walk(content, func(name string, size int64, open func() (io.Reader, error)) (bool, error) {
+		if IsSubtitle(name) {
+			files = append(files, File{Name: name, Size: size})
+		}
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Name < files[j].Name
+	})
+	return files, nil
+}
+
+// Extract returns the content of the file called name in an archive
+func Extract(content []byte, name string) ([]byte, error) {
+	var data []byte
+	found := false
+
+	err := walk(content, func(entryName string, size int64, open func() (io.Reader, error)) (bool, error) {
+		if entryName != name {
+			return true, nil
+		}
+		found = true
+
+		r, err := open()
+		if err != nil {
+			return false, err
+		}
+		data, err = readLimited(r, MaxFileSize)
+		return false, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("%w: %s", ErrFileNotFound, name)
+	}
+
+	return data, nil
+}
+
+// Entry is a subtitle file extracted from an archive
+type Entry struct {
+	File
+	Data []byte
+}
+
+// ExtractAll returns every subtitle file in an archive sorted by name. Archives holding
+// more than MaxFiles subtitle files or MaxTotalSize bytes of them fail with ErrFileTooLarge.
+func ExtractAll(content []byte) ([]Entry, error) {
+	var entries []Entry
+	var total int64
+	err := walk(content, func(name string, size int64, open func() (io.Reader, error)) (bool, error) {
+		if !IsSubtitle(name) {
+			return true, nil
+		}
+		if len(entries) == MaxFiles {
+			return false, fmt.Errorf("%w: more than %d subtitle files", ErrFileTooLarge, MaxFiles)
+		}
+
+		r, err := open()
+		if err != nil {
+			return false, err
+		}
+		data, err := readLimited(r, min(MaxFileSize, MaxTotalSize-total))
+		if err != nil {
+			return false, err
+		}
+		total += int64(len(data))
+
+		entries = append(entries, Entry{File: File{Name: name, Size: int64(len(data))}, Data: data})
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name < entries[j].Name
+	})
+	return entries, nil
+}
+
+// walk calls fn for every regular file in the archive until fn returns false.
+// The reader returned by open is only valid during the call.
+func walk(content []byte, fn func(name string, size int64, open func() (io.Reader, error)) (bool, error)) error {
+	switch Detect(content) {
+	case FormatZIP:
+		return walkZIP(content, fn)
+	case FormatRAR:
+		return walkRAR(content, fn)
+	default:
+		return ErrUnsupported
+	}
+}
+
+func walkZIP(content []byte, fn func(string, int64, func() (io.Reader, error)) (bool, error)) error {
+	zr, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		return fmt.Errorf("failed to open zip archive: %w", err)
+	}
+
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+
+		var rc io.ReadCloser
+		open := func() (io.Reader, error) {
+			var err error
+			rc, err = f.Open()
+			if err != nil {
+				return nil, fmt.Errorf("failed to open %s: %w", f.Name, err)
+			}
+			return rc, nil
+		}
+
+		more, err := fn(f.Name, int64(f.UncompressedSize64), open)
+		if rc != nil {
+			rc.Close()
+		}
+		if err != nil || !more {
+			return err
+		}
+	}
+	return nil
+}
+
+func walkRAR(content []byte, fn func(string, int64, func() (io.Reader, error)) (bool, error)) error {
+	rr, err := rardecode.NewReader(bytes.NewReader(content), "")
+	if err != nil {
+		return fmt.Errorf("failed to open rar archive: %w", err)
+	}
+
+	for {
+		header, err := rr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read rar archive: %w", err)
+		}
+		if header.IsDir {
+			continue
+		}
+
+		// RAR stores Windows paths
+		name := strings.ReplaceAll(header.Name, "\\", "/")
+		more, err := fn(name, header.UnPackedSize, func() (io.Reader, error) { return rr, nil })
+		if err != nil || !more {
+			return err
+		}
+	}
+}
+
+// readLimited reads r, failing with ErrFileTooLarge once it holds more than limit bytes
+func readLimited(r io.Reader, limit int64) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract file: %w", err)
+	}
+	if int64(len(data)) > limit {
+		return nil, ErrFileTooLarge
+	}
+	return data, nil
+}
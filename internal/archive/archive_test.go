@@ -0,0 +1,163 @@
+package archive
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"strings"
+	"testing"
+)
+
+func buildZIP(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("Create(%s) failed: %v", name, err)
+		}
+		w.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("Close() failed: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// buildRAR writes a RAR 4 archive with the files stored uncompressed
+func buildRAR(files [][2]string) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("Rar!\x1a\x07\x00")
+
+	writeBlock := func(header []byte) {
+		crc := crc32.ChecksumIEEE(header)
+		binary.Write(&buf, binary.LittleEndian, uint16(crc))
+		buf.Write(header)
+	}
+	block := func(headType byte, flags uint16, body []byte) []byte {
+		header := []byte{headType}
+		header = binary.LittleEndian.AppendUint16(header, flags)
+		header = binary.LittleEndian.AppendUint16(header, uint16(2+len(header)+2+len(body)))
+		return append(header, body...)
+	}
+
+	writeBlock(block(0x73, 0, make([]byte, 6)))
+
+	for _, file := range files {
+		name, content := file[0], file[1]
+
+		var body []byte
+		body = binary.LittleEndian.AppendUint32(body, uint32(len(content)))
+		body = binary.LittleEndian.AppendUint32(body, uint32(len(content)))
+		body = append(body, 2) // host OS: Windows
+		body = binary.LittleEndian.AppendUint32(body, crc32.ChecksumIEEE([]byte(content)))
+		body = binary.LittleEndian.AppendUint32(body, 0x5a210000) // DOS time
+		body = append(body, 29, 0x30)                             // version, method: store
+		body = binary.LittleEndian.AppendUint16(body, uint16(len(name)))
+		body = binary.LittleEndian.AppendUint32(body, 0x20) // attributes
+		body = append(body, name...)
+
+		writeBlock(block(0x74, 0x8000, body))
+		buf.WriteString(content)
+	}
+
+	writeBlock(block(0x7b, 0x4000, nil))
+	return buf.Bytes()
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name    string
+		content []byte
+		want    string
+	}{
+		{"zip", buildZIP(t, map[string]string{"a.srt": "x"}), FormatZIP},
+		{"rar", buildRAR([][2]string{{"a.srt", "x"}}), FormatRAR},
+		{"subtitle", []byte("1\n00:00:01,000 --> 00:00:02,000\nHi\n"), ""},
+	}
+
+	for _, tt := range tests {
+		if got := Detect(tt.content); got != tt.want {
+			t.Errorf("Detect(%s) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestListAndExtract_ZIP(t *testing.T) {
+	content := buildZIP(t, map[string]string{
+		"Movie/Movie.Sinhala.srt": "sinhala",
+		"Movie/readme.txt":        "visit our site",
+		"Movie.ass":               "ass subtitle",
+	})
+
+	files, err := List(content)
+	if err != nil {
+		t.Fatalf("List() failed: %v", err)
+	}
+	if len(files) != 2 || files[0].Name != "Movie.ass" || files[1].Name != "Movie/Movie.Sinhala.srt" || files[1].Size != 7 {
+		t.Errorf("List() = %+v, want the two subtitle files sorted by name", files)
+	}
+
+	data, err := Extract(content, "Movie/Movie.Sinhala.srt")
+	if err != nil || string(data) != "sinhala" {
+		t.Errorf("Extract() = %q, %v", data, err)
+	}
+
+	if _, err := Extract(content, "missing.srt"); !errors.Is(err, ErrFileNotFound) {
+		t.Errorf("Expected ErrFileNotFound, got %v", err)
+	}
+}
+
+func TestListAndExtract_RAR(t *testing.T) {
+	content := buildRAR([][2]string{
+		{`Movie\Movie.Sinhala.srt`, "sinhala"},
+		{`Movie\cover.jpg`, "jpeg"},
+		{`Movie\Movie.vtt`, "WEBVTT"},
+	})
+
+	files, err := List(content)
+	if err != nil {
+		t.Fatalf("List() failed: %v", err)
+	}
+	if len(files) != 2 || files[0].Name != "Movie/Movie.Sinhala.srt" || files[1].Name != "Movie/Movie.vtt" {
+		t.Errorf("List() = %+v, want the two subtitle files with forward slashes", files)
+	}
+
+	data, err := Extract(content, "Movie/Movie.vtt")
+	if err != nil || string(data) != "WEBVTT" {
+		t.Errorf("Extract() = %q, %v", data, err)
+	}
+
+	entries, err := ExtractAll(content)
+	if err != nil || len(entries) != 2 || string(entries[0].Data) != "sinhala" || entries[1].Size != 6 {
+		t.Errorf("ExtractAll() = %+v, %v", entries, err)
+	}
+}
+
+func TestList_Unsupported(t *testing.T) {
+	if _, err := List([]byte("not an archive")); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("Expected ErrUnsupported, got %v", err)
+	}
+}
+
+func TestExtractAll_Limits(t *testing.T) {
+	many := make(map[string]string)
+	for i := 0; i <= MaxFiles; i++ {
+		many[fmt.Sprintf("Episode %03d.srt", i)] = "1"
+	}
+	if _, err := ExtractAll(buildZIP(t, many)); !errors.Is(err, ErrFileTooLarge) {
+		t.Errorf("Expected ErrFileTooLarge for more than %d files, got %v", MaxFiles, err)
+	}
+
+	// Every file is within MaxFileSize, together they are over MaxTotalSize
+	large := strings.Repeat("0", MaxFileSize)
+	big := map[string]string{"a.srt": large, "b.srt": large, "c.srt": large}
+	if _, err := ExtractAll(buildZIP(t, big)); !errors.Is(err, ErrFileTooLarge) {
+		t.Errorf("Expected ErrFileTooLarge for more than %d bytes, got %v", MaxTotalSize, err)
+	}
+}
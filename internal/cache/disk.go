@@ -0,0 +1,338 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+const diskIndexFile = "index.json"
+
+// diskIndexSaveDelay is how long index changes are collected before the index is rewritten
+const diskIndexSaveDelay = 5 * time.Second
+
+// Disk is a Store on disk that survives restarts. Values are stored once per SHA-256
+// hash under blobs/, so the same archive served for several keys takes space once.
+// An index maps every key to its blob and when it was last used. Changes to the index
+// are batched and written in the background, Flush and Close write them right away.
+type Disk struct {
+	mu       sync.Mutex
+	dir      string
+	maxBytes int64
+	maxAge   time.Duration
+	entries  map[string]*diskEntry
+	now      func() time.Time
+
+	saveDelay time.Duration
+	saveTimer *time.Timer
+	dirty     bool
+	closed    bool
+}
+
+type diskEntry struct {
+	Hash     string    `json:"hash"`
+	Size     int64     `json:"size"`
+	StoredAt time.Time `json:"stored_at"`
+	UsedAt   time.Time `json:"used_at"`
+}
+
+// OpenDisk opens the cache in dir, creating it if needed. A missing or unreadable index
+// starts an empty cache.
+func OpenDisk(dir string, maxBytes int64, maxAge time.Duration) (*Disk, error) {
+	if err := os.MkdirAll(filepath.Join(dir, "blobs"), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	d := &Disk{
+		dir:      dir,
+		maxBytes: maxBytes,
+		maxAge:   maxAge,
+		entries:  make(map[string]*diskEntry),
+		now:      time.Now,
+
+		saveDelay: diskIndexSaveDelay,
+	}
+
+	if data, err := os.ReadFile(filepath.Join(dir, diskIndexFile)); err == nil {
+		if err := json.Unmarshal(data, &d.entries); err != nil {
+			d.entries = make(map[string]*diskEntry)
+		}
+	}
+
+	indexed := len(d.entries)
+	for key, entry := range d.entries {
+		if _, err := os.Stat(d.blobPath(entry.Hash)); err != nil {
+			delete(d.entries, key)
+		}
+	}
+
+	d.evict()
+	if err := d.removeOrphanBlobs(); err != nil {
+		return nil, err
+	}
+	if len(d.entries) != indexed {
+		d.scheduleSave()
+	}
+
+	return d, nil
+}
+
+func (d *Disk) Get(key string) ([]byte, time.Time, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	entry, exists := d.entries[key]
+	if !exists {
+		return nil, time.Time{}, false
+	}
+
+	if d.expired(entry) {
+		d.remove(key)
+		d.scheduleSave()
+		return nil, time.Time{}, false
+	}
+
+	value, err := os.ReadFile(d.blobPath(entry.Hash))
+	if err != nil {
+		d.remove(key)
+		d.scheduleSave()
+		return nil, time.Time{}, false
+	}
+
+	entry.UsedAt = d.now()
+	d.scheduleSave()
+	return value, entry.StoredAt, true
+}
+
+func (d *Disk) Set(key string, value []byte) error {
+	size := int64(len(value))
+	if d.maxBytes > 0 && size > d.maxBytes {
+		return nil
+	}
+
+	sum := sha256.Sum256(value)
+	hash := hex.EncodeToString(sum[:])
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := d.writeBlob(hash, value); err != nil {
+		return err
+	}
+
+	if previous, exists := d.entries[key]; exists && previous.Hash != hash {
+		d.remove(key)
+	}
+
+	now := d.now()
+	d.entries[key] = &diskEntry{
+		Hash:     hash,
+		Size:     size,
+		StoredAt: now,
+		UsedAt:   now,
+	}
+
+	d.evict()
+	d.scheduleSave()
+	return nil
+}
+
+func (d *Disk) Delete(key string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if _, exists := d.entries[key]; !exists {
+		return nil
+	}
+	d.remove(key)
+	d.scheduleSave()
+	return nil
+}
+
+// Flush writes pending index changes to disk
+func (d *Disk) Flush() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.flush()
+}
+
+// Close stops the background index saves and writes pending changes. Changes made
+// after Close are only written by Flush.
+func (d *Disk) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.closed = true
+	if d.saveTimer != nil {
+		d.saveTimer.Stop()
+		d.saveTimer = nil
+	}
+	return d.flush()
+}
+
+// scheduleSave marks the index as changed and starts the timer that writes it, unless
+// one is already running
+func (d *Disk) scheduleSave() {
+	d.dirty = true
+	if d.closed || d.saveTimer != nil {
+		return
+	}
+
+	d.saveTimer = time.AfterFunc(d.saveDelay, func() {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+
+		d.saveTimer = nil
+		// A failed save leaves the index dirty, so it is retried on the next change or Flush
+		d.flush()
+	})
+}
+
+func (d *Disk) flush() error {
+	if !d.dirty {
+		return nil
+	}
+	if err := d.saveIndex(); err != nil {
+		return err
+	}
+	d.dirty = false
+	return nil
+}
+
+// Size returns the bytes used by the stored values
+func (d *Disk) Size() int64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.size()
+}
+
+func (d *Disk) size() int64 {
+	var total int64
+	seen := make(map[string]bool)
+	for _, entry := range d.entries {
+		if !seen[entry.Hash] {
+			seen[entry.Hash] = true
+			total += entry.Size
+		}
+	}
+	return total
+}
+
+func (d *Disk) expired(entry *diskEntry) bool {
+	return d.maxAge > 0 && d.now().Sub(entry.StoredAt) >= d.maxAge
+}
+
+// evict drops expired keys, then the least recently used keys until the cache fits maxBytes
+func (d *Disk) evict() {
+	for key, entry := range d.entries {
+		if d.expired(entry) {
+			d.remove(key)
+		}
+	}
+
+	if d.maxBytes <= 0 || d.size() <= d.maxBytes {
+		return
+	}
+
+	keys := make([]string, 0, len(d.entries))
+	for key := range d.entries {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return d.entries[keys[i]].UsedAt.Before(d.entries[keys[j]].UsedAt)
+	})
+
+	for _, key := range keys {
+		if d.size() <= d.maxBytes {
+			break
+		}
+		d.remove(key)
+	}
+}
+
+// remove drops key and deletes its blob once no other key refers to it
+func (d *Disk) remove(key string) {
+	entry, exists := d.entries[key]
+	if !exists {
+		return
+	}
+	delete(d.entries, key)
+
+	for _, other := range d.entries {
+		if other.Hash == entry.Hash {
+			return
+		}
+	}
+	os.Remove(d.blobPath(entry.Hash))
+}
+
+func (d *Disk) removeOrphanBlobs() error {
+	referenced := make(map[string]bool)
+	for _, entry := range d.entries {
+		referenced[entry.Hash] = true
+	}
+
+	blobs, err := os.ReadDir(filepath.Join(d.dir, "blobs"))
+	if err != nil {
+		return fmt.Errorf("failed to read cache directory: %w", err)
+	}
+	for _, blob := range blobs {
+		if !referenced[blob.Name()] {
+			os.Remove(filepath.Join(d.dir, "blobs", blob.Name()))
+		}
+	}
+	return nil
+}
+
+func (d *Disk) writeBlob(hash string, value []byte) error {
+	path := d.blobPath(hash)
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to check cache file: %w", err)
+	}
+
+	return writeFileAtomic(path, value)
+}
+
+func (d *Disk) saveIndex() error {
+	data, err := json.Marshal(d.entries)
+	if err != nil {
+		return fmt.Errorf("failed to encode cache index: %w", err)
+	}
+	return writeFileAtomic(filepath.Join(d.dir, diskIndexFile), data)
+}
+
+func (d *Disk) blobPath(hash string) string {
+	return filepath.Join(d.dir, "blobs", hash)
+}
+
+// writeFileAtomic writes through a temporary file, so readers never see a partial file
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create cache file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write cache file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write cache file: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write cache file: %w", err)
+	}
+	return nil
+}
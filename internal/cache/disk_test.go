@@ -0,0 +1,210 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDisk_PutGet(t *testing.T) {
+	d, err := OpenDisk(t.TempDir(), 1<<20, time.Hour)
+	if err != nil {
+		t.Fatalf("OpenDisk() failed: %v", err)
+	}
+
+	if err := d.Set("cineru|https://cineru.lk/a", []byte("archive")); err != nil {
+		t.Fatalf("Set() failed: %v", err)
+	}
+
+	value, storedAt, ok := d.Get("cineru|https://cineru.lk/a")
+	if !ok || string(value) != "archive" || storedAt.IsZero() {
+		t.Errorf("Get() = %q, %v, %v", value, storedAt, ok)
+	}
+	if _, _, ok := d.Get("missing"); ok {
+		t.Error("Expected a miss for an unknown key")
+	}
+
+	if err := d.Delete("cineru|https://cineru.lk/a"); err != nil {
+		t.Fatalf("Delete() failed: %v", err)
+	}
+	if _, _, ok := d.Get("cineru|https://cineru.lk/a"); ok {
+		t.Error("Expected a miss after Delete")
+	}
+}
+
+func TestDisk_StoresIdenticalContentOnce(t *testing.T) {
+	dir := t.TempDir()
+	d, err := OpenDisk(dir, 1<<20, time.Hour)
+	if err != nil {
+		t.Fatalf("OpenDisk() failed: %v", err)
+	}
+
+	d.Set("a", []byte("same"))
+	d.Set("b", []byte("same"))
+
+	blobs, _ := os.ReadDir(filepath.Join(dir, "blobs"))
+	if len(blobs) != 1 || d.Size() != 4 {
+		t.Errorf("Expected one blob of 4 bytes, got %d blobs and %d bytes", len(blobs), d.Size())
+	}
+
+	d.Delete("a")
+	if value, _, ok := d.Get("b"); !ok || string(value) != "same" {
+		t.Errorf("Expected the shared blob to stay while another key uses it, got %q, %v", value, ok)
+	}
+}
+
+func TestDisk_EvictsLeastRecentlyUsed(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	d, err := OpenDisk(dir, 10, time.Hour)
+	if err != nil {
+		t.Fatalf("OpenDisk() failed: %v", err)
+	}
+	d.now = func() time.Time { return now }
+
+	d.Set("a", []byte("aaaa"))
+	now = now.Add(time.Second)
+	d.Set("b", []byte("bbbb"))
+	now = now.Add(time.Second)
+	d.Get("a")
+	now = now.Add(time.Second)
+	d.Set("c", []byte("cccc"))
+
+	if _, _, ok := d.Get("b"); ok {
+		t.Error("Expected b to be evicted as the least recently used key")
+	}
+	if _, _, ok := d.Get("a"); !ok {
+		t.Error("Expected a to be kept after it was read")
+	}
+
+	blobs, _ := os.ReadDir(filepath.Join(dir, "blobs"))
+	if len(blobs) != 2 {
+		t.Errorf("Expected the evicted blob to be deleted, got %d blobs", len(blobs))
+	}
+
+	if err := d.Set("big", []byte("too large for the cache")); err != nil {
+		t.Errorf("Set() of an oversized value failed: %v", err)
+	}
+	if _, _, ok := d.Get("big"); ok {
+		t.Error("Expected values larger than the cache not to be stored")
+	}
+}
+
+func TestDisk_Expiry(t *testing.T) {
+	now := time.Now()
+	d, err := OpenDisk(t.TempDir(), 0, time.Hour)
+	if err != nil {
+		t.Fatalf("OpenDisk() failed: %v", err)
+	}
+	d.now = func() time.Time { return now }
+
+	d.Set("a", []byte("a"))
+	now = now.Add(time.Hour)
+
+	if _, _, ok := d.Get("a"); ok {
+		t.Error("Expected the entry to expire after maxAge")
+	}
+}
+
+func TestDisk_Reopen(t *testing.T) {
+	dir := t.TempDir()
+	d, err := OpenDisk(dir, 1<<20, time.Hour)
+	if err != nil {
+		t.Fatalf("OpenDisk() failed: %v", err)
+	}
+	d.Set("a", []byte("archive"))
+	if err := d.Close(); err != nil {
+		t.Fatalf("Close() failed: %v", err)
+	}
+	os.WriteFile(filepath.Join(dir, "blobs", "orphan"), []byte("x"), 0o644)
+
+	reopened, err := OpenDisk(dir, 1<<20, time.Hour)
+	if err != nil {
+		t.Fatalf("OpenDisk() failed: %v", err)
+	}
+	if value, _, ok := reopened.Get("a"); !ok || string(value) != "archive" {
+		t.Errorf("Expected the entry to survive a reopen, got %q, %v", value, ok)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "blobs", "orphan")); !os.IsNotExist(err) {
+		t.Error("Expected blobs without a key to be removed on open")
+	}
+}
+
+func TestDisk_BatchesIndexSaves(t *testing.T) {
+	dir := t.TempDir()
+	d, err := OpenDisk(dir, 1<<20, time.Hour)
+	if err != nil {
+		t.Fatalf("OpenDisk() failed: %v", err)
+	}
+	defer d.Close()
+
+	d.Set("a", []byte("a"))
+	d.Set("b", []byte("b"))
+	if _, err := os.Stat(filepath.Join(dir, diskIndexFile)); !os.IsNotExist(err) {
+		t.Fatalf("Expected the index not to be written on every Set, got %v", err)
+	}
+
+	if err := d.Flush(); err != nil {
+		t.Fatalf("Flush() failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, diskIndexFile)); err != nil {
+		t.Errorf("Expected Flush to write the index: %v", err)
+	}
+}
+
+func TestDisk_SavesIndexInBackground(t *testing.T) {
+	dir := t.TempDir()
+	d, err := OpenDisk(dir, 1<<20, time.Hour)
+	if err != nil {
+		t.Fatalf("OpenDisk() failed: %v", err)
+	}
+	defer d.Close()
+	d.saveDelay = 10 * time.Millisecond
+
+	d.Set("a", []byte("a"))
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, err := os.Stat(filepath.Join(dir, diskIndexFile)); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the index to be written after the save delay")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestDisk_ReopenKeepsRecentUse(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now().Add(-time.Minute)
+	d, err := OpenDisk(dir, 10, time.Hour)
+	if err != nil {
+		t.Fatalf("OpenDisk() failed: %v", err)
+	}
+	d.now = func() time.Time { return now }
+
+	d.Set("a", []byte("aaaa"))
+	now = now.Add(time.Second)
+	d.Set("b", []byte("bbbb"))
+	now = now.Add(time.Second)
+	d.Get("a")
+	if err := d.Close(); err != nil {
+		t.Fatalf("Close() failed: %v", err)
+	}
+
+	reopened, err := OpenDisk(dir, 10, time.Hour)
+	if err != nil {
+		t.Fatalf("OpenDisk() failed: %v", err)
+	}
+	defer reopened.Close()
+
+	reopened.Set("c", []byte("cccc"))
+	if _, _, ok := reopened.Get("b"); ok {
+		t.Error("Expected b to be evicted, a was read after it before the restart")
+	}
+	if _, _, ok := reopened.Get("a"); !ok {
+		t.Error("Expected a to be kept, its last use should survive the restart")
+	}
+}
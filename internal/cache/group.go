@@ -0,0 +1,54 @@
+package cache
+
+import (
+	"context"
+	"sync"
+)
+
+// Group coalesces concurrent calls with the same key into a single call
+type Group[V any] struct {
+	mu    sync.Mutex
+	calls map[string]*call[V]
+}
+
+type call[V any] struct {
+	done  chan struct{}
+	value V
+	err   error
+}
+
+// Do runs fn for key unless a call for key is already in flight, in which case it waits
+// for that call and returns its result. fn runs in its own goroutine so it is not
+// interrupted when the caller that started it gives up; every caller stops waiting when
+// its ctx is done. shared reports whether the result came from another caller's fn.
+func (g *Group[V]) Do(ctx context.Context, key string, fn func() (V, error)) (value V, shared bool, err error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*call[V])
+	}
+
+	c, inFlight := g.calls[key]
+	if !inFlight {
+		c = &call[V]{done: make(chan struct{})}
+		g.calls[key] = c
+
+		go func() {
+			c.value, c.err = fn()
+
+			g.mu.Lock()
+			delete(g.calls, key)
+			g.mu.Unlock()
+
+			close(c.done)
+		}()
+	}
+	g.mu.Unlock()
+
+	select {
+	case <-c.done:
+		return c.value, inFlight, c.err
+	case <-ctx.Done():
+		var zero V
+		return zero, inFlight, ctx.Err()
+	}
+}
@@ -0,0 +1,79 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGroup_CoalescesConcurrentCalls(t *testing.T) {
+	var group Group[string]
+	var calls atomic.Int32
+	release := make(chan struct{})
+
+	fn := func() (string, error) {
+		calls.Add(1)
+		<-release
+		return "result", nil
+	}
+
+	var wg sync.WaitGroup
+	var shared atomic.Int32
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			value, wasShared, err := group.Do(context.Background(), "key", fn)
+			if err != nil || value != "result" {
+				t.Errorf("Do() = %q, %v", value, err)
+			}
+			if wasShared {
+				shared.Add(1)
+			}
+		}()
+	}
+
+	// Let every caller join the call in flight before it finishes
+	for calls.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if calls.Load() != 1 {
+		t.Errorf("fn ran %d times, want 1", calls.Load())
+	}
+	if shared.Load() != 19 {
+		t.Errorf("%d callers shared the result, want 19", shared.Load())
+	}
+}
+
+func TestGroup_CallerGivingUpDoesNotCancelCall(t *testing.T) {
+	var group Group[int]
+	release := make(chan struct{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, _, err := group.Do(ctx, "key", func() (int, error) {
+		<-release
+		return 42, nil
+	}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected the cancelled caller to stop waiting, got %v", err)
+	}
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		close(release)
+	}()
+
+	value, shared, err := group.Do(context.Background(), "key", func() (int, error) {
+		return 0, errors.New("should have joined the running call")
+	})
+	if err != nil || value != 42 || !shared {
+		t.Errorf("Do() = %v, %v, %v, want the shared result of the running call", value, shared, err)
+	}
+}
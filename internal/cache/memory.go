@@ -0,0 +1,105 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// Memory is an in-memory Store bounded by the total size of its values
+type Memory struct {
+	mu       sync.Mutex
+	maxBytes int64
+	maxAge   time.Duration
+	size     int64
+	items    map[string]*list.Element
+	// order holds the entries from most to least recently used
+	order *list.List
+	now   func() time.Time
+}
+
+type memoryEntry struct {
+	key      string
+	value    []byte
+	storedAt time.Time
+}
+
+func NewMemory(maxBytes int64, maxAge time.Duration) *Memory {
+	return &Memory{
+		maxBytes: maxBytes,
+		maxAge:   maxAge,
+		items:    make(map[string]*list.Element),
+		order:    list.New(),
+		now:      time.Now,
+	}
+}
+
+func (m *Memory) Get(key string) ([]byte, time.Time, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	element, exists := m.items[key]
+	if !exists {
+		return nil, time.Time{}, false
+	}
+
+	e := element.Value.(*memoryEntry)
+	if m.maxAge > 0 && m.now().Sub(e.storedAt) >= m.maxAge {
+		m.remove(element)
+		return nil, time.Time{}, false
+	}
+
+	m.order.MoveToFront(element)
+	return e.value, e.storedAt, true
+}
+
+func (m *Memory) Set(key string, value []byte) error {
+	size := int64(len(value))
+	if m.maxBytes > 0 && size > m.maxBytes {
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if element, exists := m.items[key]; exists {
+		m.remove(element)
+	}
+
+	m.items[key] = m.order.PushFront(&memoryEntry{key: key, value: value, storedAt: m.now()})
+	m.size += size
+
+	for m.maxBytes > 0 && m.size > m.maxBytes {
+		m.remove(m.order.Back())
+	}
+	return nil
+}
+
+func (m *Memory) Delete(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if element, exists := m.items[key]; exists {
+		m.remove(element)
+	}
+	return nil
+}
+
+// Close does nothing, a memory store has nothing to write out
+func (m *Memory) Close() error {
+	return nil
+}
+
+// Len returns the number of entries, including expired ones not evicted yet
+func (m *Memory) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.order.Len()
+}
+
+func (m *Memory) remove(element *list.Element) {
+	e := element.Value.(*memoryEntry)
+	m.order.Remove(element)
+	delete(m.items, e.key)
+	m.size -= int64(len(e.value))
+}
@@ -0,0 +1,84 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemory_GetSet(t *testing.T) {
+	m := NewMemory(1<<10, time.Minute)
+
+	m.Set("a", []byte("1"))
+	if value, storedAt, ok := m.Get("a"); !ok || string(value) != "1" || storedAt.IsZero() {
+		t.Errorf("Get(a) = %q, %v, %v", value, storedAt, ok)
+	}
+	if _, _, ok := m.Get("missing"); ok {
+		t.Error("Expected a miss for an unknown key")
+	}
+
+	m.Set("a", []byte("2"))
+	if value, _, _ := m.Get("a"); string(value) != "2" || m.Len() != 1 {
+		t.Errorf("Expected Set to replace the value, got %q with %d entries", value, m.Len())
+	}
+
+	m.Delete("a")
+	if _, _, ok := m.Get("a"); ok {
+		t.Error("Expected a miss after Delete")
+	}
+}
+
+func TestMemory_EvictsLeastRecentlyUsed(t *testing.T) {
+	m := NewMemory(8, time.Minute)
+
+	m.Set("a", []byte("aaaa"))
+	m.Set("b", []byte("bbbb"))
+	m.Get("a")
+	m.Set("c", []byte("cccc"))
+
+	if _, _, ok := m.Get("b"); ok {
+		t.Error("Expected b to be evicted as the least recently used entry")
+	}
+	if _, _, ok := m.Get("a"); !ok {
+		t.Error("Expected a to be kept after it was read")
+	}
+
+	m.Set("big", []byte("larger than the store"))
+	if _, _, ok := m.Get("big"); ok || m.Len() != 2 {
+		t.Errorf("Expected values larger than the store to be skipped, got %d entries", m.Len())
+	}
+}
+
+func TestMemory_Expiry(t *testing.T) {
+	now := time.Now()
+	m := NewMemory(0, time.Minute)
+	m.now = func() time.Time { return now }
+
+	m.Set("a", []byte("1"))
+	now = now.Add(59 * time.Second)
+	if _, _, ok := m.Get("a"); !ok {
+		t.Error("Expected the entry before its max age")
+	}
+
+	now = now.Add(time.Second)
+	if _, _, ok := m.Get("a"); ok {
+		t.Error("Expected the entry to expire after its max age")
+	}
+	if m.Len() != 0 {
+		t.Errorf("Expected expired entries to be removed on read, got %d", m.Len())
+	}
+}
+
+func TestOpen(t *testing.T) {
+	if _, err := Open(BackendMemory, "", 1, time.Minute); err != nil {
+		t.Errorf("Open(memory) failed: %v", err)
+	}
+	if _, err := Open(BackendDisk, t.TempDir(), 1, time.Minute); err != nil {
+		t.Errorf("Open(disk) failed: %v", err)
+	}
+	if _, err := Open(BackendDisk, "", 1, time.Minute); err == nil {
+		t.Error("Expected an error for a disk store without a directory")
+	}
+	if _, err := Open("redis", "", 1, time.Minute); err == nil {
+		t.Error("Expected an error for an unknown backend")
+	}
+}
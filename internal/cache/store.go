@@ -0,0 +1,47 @@
+// Package cache provides the byte stores behind the search and download caches, and
+// coalescing of concurrent calls for the same key.
+package cache
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	BackendMemory = "memory"
+	BackendDisk   = "disk"
+)
+
+// Store is a size-bounded key-value store whose entries expire after a maximum age.
+// Implementations evict the least recently used entries when full and are safe for
+// concurrent use.
+type Store interface {
+	// Get returns the value stored under key and when it was stored
+	Get(key string) ([]byte, time.Time, bool)
+	// Set stores value under key. Values larger than the whole store are not stored.
+	Set(key string, value []byte) error
+	Delete(key string) error
+	// Close writes out anything the store still holds in memory only
+	Close() error
+}
+
+// Open creates a store of the given backend. Disk stores keep their files in dir,
+// memory stores ignore it.
+func Open(backend, dir string, maxBytes int64, maxAge time.Duration) (Store, error) {
+	switch backend {
+	case BackendMemory, "":
+		return NewMemory(maxBytes, maxAge), nil
+	case BackendDisk:
+		if dir == "" {
+			return nil, fmt.Errorf("disk cache needs a directory")
+		}
+		return OpenDisk(dir, maxBytes, maxAge)
+	default:
+		return nil, fmt.Errorf("unknown cache backend %q", backend)
+	}
+}
+
+var (
+	_ Store = (*Memory)(nil)
+	_ Store = (*Disk)(nil)
+)
@@ -0,0 +1,148 @@
+// Package charset detects the character encoding of subtitle files
+package charset
+
+import (
+	"bytes"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+const (
+	UTF8        = "utf-8"
+	UTF8BOM     = "utf-8-bom"
+	UTF16LE     = "utf-16le"
+	UTF16BE     = "utf-16be"
+	Windows1252 = "windows-1252"
+)
+
+var (
+	bomUTF8    = []byte{0xEF, 0xBB, 0xBF}
+	bomUTF16LE = []byte{0xFF, 0xFE}
+	bomUTF16BE = []byte{0xFE, 0xFF}
+)
+
+// Detect returns the encoding of data: UTF-8 with or without a byte order mark, UTF-16
+// with a byte order mark or mostly ASCII text, and Windows-1252 for anything else
+func Detect(data []byte) string {
+	switch {
+	case bytes.HasPrefix(data, bomUTF8):
+		return UTF8BOM
+	case bytes.HasPrefix(data, bomUTF16LE):
+		return UTF16LE
+	case bytes.HasPrefix(data, bomUTF16BE):
+		return UTF16BE
+	}
+
+	if encoding := detectUTF16(data); encoding != "" {
+		return encoding
+	}
+	if utf8.Valid(data) {
+		return UTF8
+	}
+	return Windows1252
+}
+
+// detectUTF16 recognises UTF-16 without a byte order mark from the zero high bytes
+// of ASCII characters, which make up most of any subtitle file
+func detectUTF16(data []byte) string {
+	if len(data) < 4 {
+		return ""
+	}
+
+	var evenZeros, oddZeros int
+	for i, b := range data {
+		if b != 0 {
+			continue
+		}
+		if i%2 == 0 {
+			evenZeros++
+		} else {
+			oddZeros++
+		}
+	}
+
+	half := len(data) / 2
+	switch {
+	case oddZeros > half*3/5 && evenZeros < half/10:
+		return UTF16LE
+	case evenZeros > half*3/5 && oddZeros < half/10:
+		return UTF16BE
+	default:
+		return ""
+	}
+}
+
+// DecodeUTF16 returns UTF-16 data as UTF-8 without the byte order mark
+func DecodeUTF16(data []byte, bigEndian bool) []byte {
+	if bytes.HasPrefix(data, bomUTF16LE) || bytes.HasPrefix(data, bomUTF16BE) {
+		data = data[2:]
+	}
+
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		if bigEndian {
+			units[i] = uint16(data[2*i])<<8 | uint16(data[2*i+1])
+		} else {
+			units[i] = uint16(data[2*i+1])<<8 | uint16(data[2*i])
+		}
+	}
+
+	return []byte(string(utf16.Decode(units)))
+}
+
+// windows1252 holds the characters Windows-1252 puts at 0x80-0x9F, where it differs
+// from Latin-1. Unassigned bytes keep their Latin-1 control characters.
+var windows1252 = [32]rune{
+	'€', 0x81, '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', 0x8D, 'Ž', 0x8F,
+	0x90, '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', 0x9D, 'ž', 'Ÿ',
+}
+
+// ToUTF8 returns data as UTF-8 without a byte order mark, along with the encoding it
+// was detected in
+func ToUTF8(data []byte) ([]byte, string) {
+	encoding := Detect(data)
+	switch encoding {
+	case UTF8BOM:
+		return data[len(bomUTF8):], encoding
+	case UTF16LE:
+		return DecodeUTF16(data, false), encoding
+	case UTF16BE:
+		return DecodeUTF16(data, true), encoding
+	case Windows1252:
+		return decodeWindows1252(data), encoding
+	default:
+		return data, encoding
+	}
+}
+
+// WithBOM returns UTF-8 data starting with a byte order mark, which some older players
+// need to read the file as UTF-8
+func WithBOM(data []byte) []byte {
+	if bytes.HasPrefix(data, bomUTF8) {
+		return data
+	}
+	return append(append([]byte{}, bomUTF8...), data...)
+}
+
+// Name returns the name of an encoding as used in a Content-Type charset parameter
+func Name(encoding string) string {
+	if encoding == UTF8BOM {
+		return UTF8
+	}
+	return encoding
+}
+
+func decodeWindows1252(data []byte) []byte {
+	out := make([]byte, 0, len(data)+len(data)/8)
+	for _, b := range data {
+		switch {
+		case b < 0x80:
+			out = append(out, b)
+		case b < 0xA0:
+			out = utf8.AppendRune(out, windows1252[b-0x80])
+		default:
+			out = utf8.AppendRune(out, rune(b))
+		}
+	}
+	return out
+}
@@ -0,0 +1,88 @@
+package charset
+
+import (
+	"testing"
+	"unicode/utf16"
+)
+
+func encodeUTF16(s string, bigEndian bool, bom bool) []byte {
+	var data []byte
+	units := utf16.Encode([]rune(s))
+	if bom {
+		units = append([]uint16{0xFEFF}, units...)
+	}
+	for _, u := range units {
+		if bigEndian {
+			data = append(data, byte(u>>8), byte(u))
+		} else {
+			data = append(data, byte(u), byte(u>>8))
+		}
+	}
+	return data
+}
+
+func TestDetect(t *testing.T) {
+	text := "1\r\n00:00:01,000 --> 00:00:02,000\r\nආයුබෝවන්\r\n"
+
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"utf-8", []byte(text), UTF8},
+		{"utf-8 with bom", append([]byte{0xEF, 0xBB, 0xBF}, text...), UTF8BOM},
+		{"utf-16le with bom", encodeUTF16(text, false, true), UTF16LE},
+		{"utf-16be with bom", encodeUTF16(text, true, true), UTF16BE},
+		{"utf-16le without bom", encodeUTF16(text, false, false), UTF16LE},
+		{"utf-16be without bom", encodeUTF16(text, true, false), UTF16BE},
+		{"windows-1252", []byte("Caf\xe9 \x93quoted\x94"), Windows1252},
+		{"ascii", []byte("plain text"), UTF8},
+	}
+
+	for _, tt := range tests {
+		if got := Detect(tt.data); got != tt.want {
+			t.Errorf("Detect(%s) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDecodeUTF16(t *testing.T) {
+	text := "Hello ආයුබෝවන් 😀"
+
+	if got := string(DecodeUTF16(encodeUTF16(text, false, true), false)); got != text {
+		t.Errorf("DecodeUTF16(le) = %q, want %q", got, text)
+	}
+	if got := string(DecodeUTF16(encodeUTF16(text, true, false), true)); got != text {
+		t.Errorf("DecodeUTF16(be) = %q, want %q", got, text)
+	}
+}
+
+func TestToUTF8(t *testing.T) {
+	text := "1\r\n00:00:01,000 --> 00:00:02,000\r\nආයුබෝවන්\r\n"
+
+	tests := []struct {
+		name     string
+		data     []byte
+		want     string
+		encoding string
+	}{
+		{"utf-8", []byte(text), text, UTF8},
+		{"utf-8 with bom", append([]byte{0xEF, 0xBB, 0xBF}, text...), text, UTF8BOM},
+		{"utf-16le", encodeUTF16(text, false, true), text, UTF16LE},
+		{"windows-1252", []byte("Caf\xe9 \x93quoted\x94 \x80"), "Café “quoted” €", Windows1252},
+	}
+
+	for _, tt := range tests {
+		got, encoding := ToUTF8(tt.data)
+		if string(got) != tt.want || encoding != tt.encoding {
+			t.Errorf("ToUTF8(%s) = %q, %q, want %q, %q", tt.name, got, encoding, tt.want, tt.encoding)
+		}
+	}
+}
+
+func TestWithBOM(t *testing.T) {
+	once := WithBOM([]byte("text"))
+	if string(once) != "\xEF\xBB\xBFtext" || string(WithBOM(once)) != string(once) {
+		t.Errorf("WithBOM() = %q, want a single byte order mark", once)
+	}
+}
@@ -3,15 +3,26 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"ipmanlk/bettercopelk/internal/archive"
+	"ipmanlk/bettercopelk/internal/charset"
 	"ipmanlk/bettercopelk/internal/models"
 	"ipmanlk/bettercopelk/internal/services"
 	"ipmanlk/bettercopelk/internal/sse"
+	"ipmanlk/bettercopelk/internal/subtitle"
+	"math"
 	"net/http"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 )
 
+// maxUploadSize bounds uploaded subtitle files, which are far smaller in practice
+const maxUploadSize = 5 << 20
+
 type SubtitleHandler struct {
 	service *services.SubtitleService
 }
@@ -43,24 +54,8 @@ func splitSources(sourcesStr string) []string {
 }
 
 func (h *SubtitleHandler) Search(w http.ResponseWriter, r *http.Request) {
-	query := r.URL.Query().Get("query")
-	if query == "" {
-		http.Error(w, "Query parameter is required", http.StatusBadRequest)
-		return
-	}
-
-	var sources []string
-	sourcesParam := r.URL.Query().Get("sources")
-	if sourcesParam != "" {
-		sources = splitSources(sourcesParam)
-	}
-
-	req := models.SearchRequest{
-		Query:   query,
-		Sources: sources,
-	}
-
-	if err := h.service.ValidateSources(sources); err != nil {
+	req, err := h.parseSearchRequest(r)
+	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
@@ -80,6 +75,7 @@ func (h *SubtitleHandler) GetAvailableSources(w http.ResponseWriter, r *http.Req
 
 	response := &models.SourcesResponse{
 		Sources: sources,
+		Details: h.service.GetSourceStatuses(),
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -87,6 +83,121 @@ func (h *SubtitleHandler) GetAvailableSources(w http.ResponseWriter, r *http.Req
 }
 
 func (h *SubtitleHandler) Download(w http.ResponseWriter, r *http.Request) {
+	req, err := h.parseDownloadRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var content []byte
+	var filename string
+	if picksFile(req) {
+		var file *models.SubtitleFile
+		file, err = h.service.DownloadFile(r.Context(), req)
+		if err == nil {
+			content, filename = file.Content, file.Filename
+			w.Header().Set("X-Detected-Encoding", file.Encoding)
+			if file.Report != nil {
+				setReportHeaders(w.Header(), file.Report)
+			}
+		}
+	} else {
+		content, filename, err = h.service.Download(r.Context(), req)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), downloadErrorStatus(err))
+		return
+	}
+
+	contentType := getContentType(filename, content)
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	w.Header().Set("Content-Length", fmt.Sprintf("%d", len(content)))
+	w.Write(content)
+}
+
+// Validate downloads a subtitle file and reports on its quality
+func (h *SubtitleHandler) Validate(w http.ResponseWriter, r *http.Request) {
+	req, err := h.parseDownloadRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	report, err := h.service.Validate(r.Context(), req)
+	if err != nil {
+		http.Error(w, err.Error(), downloadErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// setReportHeaders summarises a subtitle report in response headers
+func setReportHeaders(header http.Header, report *models.SubtitleReport) {
+	header.Set("X-Subtitle-Format", report.Format)
+	header.Set("X-Subtitle-Cues", strconv.Itoa(report.Cues))
+	header.Set("X-Subtitle-Span", report.Start+"-"+report.End)
+	header.Set("X-Subtitle-Sinhala-Percent", strconv.FormatFloat(report.SinhalaPercent, 'f', -1, 64))
+	header.Set("X-Subtitle-Issues", strconv.Itoa(len(report.Issues)))
+}
+
+// Merge downloads a subtitle file and merges the English subtitle uploaded with the form into it
+func (h *SubtitleHandler) Merge(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize)
+	if err := r.ParseMultipartForm(maxUploadSize); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, fmt.Sprintf("upload is larger than %d bytes", tooLarge.Limit), http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "invalid form: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	download, err := h.parseDownloadRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if download.Format != "" && download.Format != subtitle.FormatSRT && download.Format != subtitle.FormatASS {
+		http.Error(w, "format must be 'srt' or 'ass'", http.StatusBadRequest)
+		return
+	}
+
+	upload, header, err := r.FormFile("english")
+	if err != nil {
+		http.Error(w, "english subtitle file is required", http.StatusBadRequest)
+		return
+	}
+	defer upload.Close()
+
+	secondary, err := io.ReadAll(upload)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	file, err := h.service.Merge(r.Context(), models.MergeRequest{
+		DownloadRequest: download,
+		Secondary:       secondary,
+		SecondaryName:   header.Filename,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), downloadErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", getContentType(file.Filename, file.Content))
+	w.Header().Set("Content-Disposition", `attachment; filename="`+file.Filename+`"`)
+	w.Header().Set("Content-Length", fmt.Sprintf("%d", len(file.Content)))
+	w.Header().Set("X-Detected-Encoding", file.Encoding)
+	w.Write(file.Content)
+}
+
+func (h *SubtitleHandler) DownloadContents(w http.ResponseWriter, r *http.Request) {
 	url := r.URL.Query().Get("url")
 	source := r.URL.Query().Get("source")
 
@@ -105,18 +216,43 @@ func (h *SubtitleHandler) Download(w http.ResponseWriter, r *http.Request) {
 		Source: source,
 	}
 
-	content, filename, err := h.service.Download(r.Context(), req)
+	response, err := h.service.DownloadContents(r.Context(), req)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), downloadErrorStatus(err))
 		return
 	}
 
-	contentType := getContentTypeFromFilename(filename)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
 
-	w.Header().Set("Content-Type", contentType)
-	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
-	w.Header().Set("Content-Length", fmt.Sprintf("%d", len(content)))
-	w.Write(content)
+func (h *SubtitleHandler) ListEpisodes(w http.ResponseWriter, r *http.Request) {
+	url := r.URL.Query().Get("url")
+	source := r.URL.Query().Get("source")
+
+	if url == "" || source == "" {
+		http.Error(w, "URL and source parameters are required", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.ValidateSources([]string{source}); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	req := models.DownloadRequest{
+		URL:    url,
+		Source: source,
+	}
+
+	episodes, err := h.service.ListEpisodes(r.Context(), req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(&models.EpisodesResponse{Episodes: episodes})
 }
 
 func (h *SubtitleHandler) SearchStream(w http.ResponseWriter, r *http.Request) {
@@ -150,18 +286,203 @@ func (h *SubtitleHandler) parseSearchRequest(r *http.Request) (models.SearchRequ
 		return models.SearchRequest{}, err
 	}
 
-	return models.SearchRequest{
+	req := models.SearchRequest{
 		Query:   query,
 		Sources: sources,
-	}, nil
+	}
+
+	params := r.URL.Query()
+
+	if groupedParam := params.Get("grouped"); groupedParam != "" {
+		grouped, err := strconv.ParseBool(groupedParam)
+		if err != nil {
+			return models.SearchRequest{}, fmt.Errorf("invalid grouped parameter: %s", groupedParam)
+		}
+		req.Grouped = grouped
+	}
+
+	switch req.Type = params.Get("type"); req.Type {
+	case "", models.TypeMovie, models.TypeTV:
+	default:
+		return models.SearchRequest{}, fmt.Errorf("invalid type parameter: %s (expected movie or tv)", req.Type)
+	}
+
+	var err error
+	if req.Year, err = parseIntParam(params.Get("year"), "year", 1900, 2100); err != nil {
+		return models.SearchRequest{}, err
+	}
+	if req.Season, err = parseIntParam(params.Get("season"), "season", 1, 100); err != nil {
+		return models.SearchRequest{}, err
+	}
+	if req.Episode, err = parseIntParam(params.Get("episode"), "episode", 1, 1000); err != nil {
+		return models.SearchRequest{}, err
+	}
+
+	return req, nil
+}
+
+// parseDownloadRequest reads the post, the file and the conversions of a download from
+// the query, or from the form of a POST request
+func (h *SubtitleHandler) parseDownloadRequest(r *http.Request) (models.DownloadRequest, error) {
+	if err := r.ParseForm(); err != nil {
+		return models.DownloadRequest{}, err
+	}
+	params := r.Form
+
+	req := models.DownloadRequest{
+		URL:      params.Get("url"),
+		Source:   params.Get("source"),
+		File:     params.Get("file"),
+		Convert:  params.Get("convert"),
+		Format:   params.Get("format"),
+		Encoding: params.Get("encoding"),
+	}
+
+	if req.URL == "" || req.Source == "" {
+		return models.DownloadRequest{}, fmt.Errorf("URL and source parameters are required")
+	}
+	if err := h.service.ValidateSources([]string{req.Source}); err != nil {
+		return models.DownloadRequest{}, err
+	}
+
+	if req.Convert != "" && req.Convert != models.ConvertUnicode {
+		return models.DownloadRequest{}, fmt.Errorf("convert must be 'unicode'")
+	}
+	switch req.Format {
+	case "", subtitle.FormatSRT, subtitle.FormatVTT, subtitle.FormatASS, subtitle.FormatSSA, subtitle.FormatMicroDVD:
+	default:
+		return models.DownloadRequest{}, fmt.Errorf("format must be one of srt, vtt, ass, ssa or microdvd")
+	}
+	if req.Encoding != "" && req.Encoding != charset.UTF8 && req.Encoding != charset.UTF8BOM {
+		return models.DownloadRequest{}, fmt.Errorf("encoding must be 'utf-8' or 'utf-8-bom'")
+	}
+
+	if repairParam := params.Get("repair"); repairParam != "" {
+		repair, err := strconv.ParseBool(repairParam)
+		if err != nil {
+			return models.DownloadRequest{}, fmt.Errorf("invalid repair parameter: %s", repairParam)
+		}
+		req.Repair = repair
+	}
+	if reportParam := params.Get("report"); reportParam != "" {
+		report, err := strconv.ParseBool(reportParam)
+		if err != nil {
+			return models.DownloadRequest{}, fmt.Errorf("invalid report parameter: %s", reportParam)
+		}
+		req.Report = report
+	}
+
+	var err error
+	if req.Offset, err = parseOffset(params.Get("offset")); err != nil {
+		return models.DownloadRequest{}, err
+	}
+	if req.FromFPS, req.ToFPS, err = parseFrameRates(params.Get("fps")); err != nil {
+		return models.DownloadRequest{}, err
+	}
+
+	if points := params["sync"]; len(points) > 0 {
+		if len(points) != 2 {
+			return models.DownloadRequest{}, fmt.Errorf("sync needs exactly two points")
+		}
+		for _, point := range points {
+			syncPoint, err := parseSyncPoint(point)
+			if err != nil {
+				return models.DownloadRequest{}, err
+			}
+			req.Sync = append(req.Sync, syncPoint)
+		}
+		if req.Sync[0].From == req.Sync[1].From {
+			return models.DownloadRequest{}, fmt.Errorf("sync points must be at different times")
+		}
+	}
+
+	return req, nil
+}
+
+// picksFile reports whether a download asks for a single subtitle file, or a conversion
+// of one, rather than the post as the source serves it
+func picksFile(req models.DownloadRequest) bool {
+	return req.File != "" || req.Convert != "" || req.Format != "" || req.Encoding != "" ||
+		req.Offset != 0 || req.FromFPS != 0 || len(req.Sync) > 0 || req.Repair || req.Report
+}
+
+// maxOffset bounds the offset parameter, far beyond the length of any video
+const maxOffset = 24 * time.Hour
+
+// parseOffset reads a signed duration such as "-1.5s" or "250ms", or a number of seconds
+func parseOffset(value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	var offset time.Duration
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+		// NaN and infinities parse as numbers, and huge values overflow the conversion
+		if math.IsNaN(seconds) || math.Abs(seconds) > maxOffset.Seconds() {
+			return 0, fmt.Errorf("offset must be at most %s either way: %s", maxOffset, value)
+		}
+		offset = time.Duration(seconds * float64(time.Second))
+	} else if offset, err = time.ParseDuration(value); err != nil {
+		return 0, fmt.Errorf("invalid offset parameter: %s", value)
+	}
+
+	if offset > maxOffset || offset < -maxOffset {
+		return 0, fmt.Errorf("offset must be at most %s either way: %s", maxOffset, value)
+	}
+	return offset, nil
+}
+
+// parseFrameRates reads "from:to" frame rates such as "25:23.976"
+func parseFrameRates(value string) (float64, float64, error) {
+	if value == "" {
+		return 0, 0, nil
+	}
+
+	fromValue, toValue, found := strings.Cut(value, ":")
+	from, fromErr := strconv.ParseFloat(fromValue, 64)
+	to, toErr := strconv.ParseFloat(toValue, 64)
+	if !found || fromErr != nil || toErr != nil || from <= 0 || to <= 0 || from > 1000 || to > 1000 {
+		return 0, 0, fmt.Errorf("invalid fps parameter: %s (expected from:to, e.g. 25:23.976)", value)
+	}
+	return from, to, nil
+}
+
+// parseSyncPoint reads "subtitle time->video time", such as "00:01:02.500->00:01:04.000"
+func parseSyncPoint(value string) (models.SyncPoint, error) {
+	fromValue, toValue, found := strings.Cut(value, "->")
+	from, fromOK := subtitle.ParseTimestamp(fromValue)
+	to, toOK := subtitle.ParseTimestamp(toValue)
+	if !found || !fromOK || !toOK {
+		return models.SyncPoint{}, fmt.Errorf("invalid sync parameter: %s (expected subtitle time->video time)", value)
+	}
+	return models.SyncPoint{From: from, To: to}, nil
+}
+
+// parseIntParam parses an optional integer query parameter within [min, max], returning 0 when it is empty
+func parseIntParam(value, name string, min, max int) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil || n < min || n > max {
+		return 0, fmt.Errorf("invalid %s parameter: %s", name, value)
+	}
+	return n, nil
 }
 
 func (h *SubtitleHandler) streamSearchResults(ctx context.Context, req models.SearchRequest, writer *sse.Writer) {
 	resultChan := make(chan models.SearchResult, 10)
-	sourceCompleteChan := make(chan models.SourceCompleteEvent, 10)
+	sourceCompleteChan := make(chan models.SourceSearchStatus, 10)
 
 	go h.service.StreamSearch(ctx, req, resultChan, sourceCompleteChan)
 
+	// Grouped streams send the updated work every time one of its variants arrives
+	var grouper *services.Grouper
+	if req.Grouped {
+		grouper = services.NewGrouper()
+	}
+
 	// Multiplex between result and source completion channels until both are closed
 	var resultsDone, sourcesDone bool
 
@@ -176,6 +497,13 @@ func (h *SubtitleHandler) streamSearchResults(ctx context.Context, req models.Se
 				continue
 			}
 
+			if grouper != nil {
+				if err := writer.WriteEvent("work", grouper.Add(result)); err != nil {
+					return
+				}
+				continue
+			}
+
 			if err := writer.WriteEvent("result", result); err != nil {
 				return
 			}
@@ -186,6 +514,12 @@ func (h *SubtitleHandler) streamSearchResults(ctx context.Context, req models.Se
 				continue
 			}
 
+			if sourceEvent.Status != models.SearchStatusOK {
+				if err := writer.WriteEvent("source-error", sourceEvent); err != nil {
+					return
+				}
+			}
+
 			if err := writer.WriteEvent("source-complete", sourceEvent); err != nil {
 				return
 			}
@@ -195,22 +529,44 @@ func (h *SubtitleHandler) streamSearchResults(ctx context.Context, req models.Se
 	writer.WriteEvent("end", map[string]interface{}{})
 }
 
-func getContentTypeFromFilename(filename string) string {
+// downloadErrorStatus maps archive and subtitle errors to client errors, anything else is a server error
+func downloadErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, subtitle.ErrInvalidTiming):
+		return http.StatusBadRequest
+	case errors.Is(err, archive.ErrFileNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, archive.ErrUnsupported), errors.Is(err, archive.ErrFileTooLarge), errors.Is(err, subtitle.ErrUnknownFormat):
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// getContentType identifies archives by their signature, whatever they are named,
+// and other files by their extension, with the charset detected from the text
+func getContentType(filename string, content []byte) string {
+	switch archive.Detect(content) {
+	case archive.FormatZIP:
+		return "application/zip"
+	case archive.FormatRAR:
+		return "application/vnd.rar"
+	}
+
 	ext := strings.ToLower(filepath.Ext(filename))
+	textCharset := "; charset=" + charset.Name(charset.Detect(content))
 
 	switch ext {
-	case ".zip":
-		return "application/zip"
 	case ".srt":
-		return "text/plain; charset=utf-8"
+		return "text/plain" + textCharset
 	case ".ass", ".ssa":
-		return "text/plain; charset=utf-8"
+		return "text/plain" + textCharset
 	case ".vtt":
-		return "text/vtt; charset=utf-8"
+		return "text/vtt" + textCharset
 	case ".sub":
-		return "text/plain; charset=utf-8"
+		return "text/plain" + textCharset
 	default:
-		return "application/zip"
+		return "application/octet-stream"
 	}
 }
 
@@ -218,5 +574,9 @@ func (h *SubtitleHandler) RegisterRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("GET /api/v1/search", h.Search)
 	mux.HandleFunc("GET /api/v1/search/stream", h.SearchStream)
 	mux.HandleFunc("GET /api/v1/download", h.Download)
+	mux.HandleFunc("GET /api/v1/download/contents", h.DownloadContents)
+	mux.HandleFunc("GET /api/v1/download/validate", h.Validate)
+	mux.HandleFunc("POST /api/v1/download/merge", h.Merge)
 	mux.HandleFunc("GET /api/v1/sources", h.GetAvailableSources)
+	mux.HandleFunc("GET /api/v1/series/episodes", h.ListEpisodes)
 }
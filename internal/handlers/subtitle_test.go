@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseOffset(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"", 0, false},
+		{"-2.5", -2500 * time.Millisecond, false},
+		{"1500ms", 1500 * time.Millisecond, false},
+		{"-1m2s", -62 * time.Second, false},
+		{"86400", 24 * time.Hour, false},
+		{"NaN", 0, true},
+		{"Inf", 0, true},
+		{"-Inf", 0, true},
+		{"1e12", 0, true},
+		{"86401", 0, true},
+		{"25h", 0, true},
+		{"-2562047h", 0, true},
+		{"soon", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseOffset(tt.value)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseOffset(%q) = %v, %v, want %v (error %v)", tt.value, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
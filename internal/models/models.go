@@ -1,28 +1,136 @@
 package models
 
+import "time"
+
+const (
+	TypeMovie = "movie"
+	TypeTV    = "tv"
+)
+
+// ConvertUnicode converts subtitle text typed in legacy Sinhala fonts to Unicode
+const ConvertUnicode = "unicode"
+
 type SearchRequest struct {
 	Query   string   `json:"query"`
 	Sources []string `json:"sources,omitempty"`
+	Grouped bool     `json:"grouped,omitempty"`
+
+	// Filters applied to the results, zero values match everything
+	Year    int    `json:"year,omitempty"`
+	Type    string `json:"type,omitempty"`
+	Season  int    `json:"season,omitempty"`
+	Episode int    `json:"episode,omitempty"`
 }
 
 type SearchResult struct {
-	Title  string `json:"title"`
-	URL    string `json:"url"`
-	Source string `json:"source"`
+	Title  string  `json:"title"`
+	URL    string  `json:"url"`
+	Source string  `json:"source"`
+	Score  float64 `json:"score"`
+
+	// Type is "tv" for series posts and "movie" otherwise
+	Type string `json:"type,omitempty"`
+
+	// Metadata parsed from the title, empty when the title does not mention it
+	Year       int      `json:"year,omitempty"`
+	Season     int      `json:"season,omitempty"`
+	Episode    int      `json:"episode,omitempty"`
+	Tags       []string `json:"tags,omitempty"`
+	Translator string   `json:"translator,omitempty"`
+
+	// Collection is set on posts bundling several films, Children holds the films found on
+	// the post. ChildrenError says why they could not be listed.
+	Collection    bool           `json:"collection,omitempty"`
+	Children      []SearchResult `json:"children,omitempty"`
+	ChildrenError string         `json:"children_error,omitempty"`
 }
 
 type SearchResponse struct {
-	Results []SearchResult `json:"results"`
+	Results []SearchResult       `json:"results"`
+	Works   []Work               `json:"works,omitempty"`
+	Sources []SourceSearchStatus `json:"sources"`
+}
+
+// Work is a single film or series with the posts found for it across sources
+type Work struct {
+	Key      string         `json:"key"`
+	Title    string         `json:"title"`
+	Year     int            `json:"year,omitempty"`
+	Score    float64        `json:"score"`
+	Variants []SearchResult `json:"variants"`
 }
 
 type DownloadRequest struct {
 	URL    string `json:"url"`
 	Source string `json:"source"`
+	// File picks a single subtitle file out of the downloaded archive
+	File string `json:"file,omitempty"`
+	// Convert is the conversion applied to the subtitle text, only ConvertUnicode is supported
+	Convert string `json:"convert,omitempty"`
+	// Format converts the subtitle file to another format: "srt", "vtt", "ass", "ssa" or "microdvd"
+	Format string `json:"format,omitempty"`
+	// Encoding re-encodes the subtitle file, "utf-8" or "utf-8-bom", empty keeps the original
+	Encoding string `json:"encoding,omitempty"`
+
+	// Timing adjustments, applied in order: the cues are converted from the FromFPS to the
+	// ToFPS frame rate, shifted by Offset, then retimed linearly from the two Sync points
+	FromFPS float64       `json:"from_fps,omitempty"`
+	ToFPS   float64       `json:"to_fps,omitempty"`
+	Offset  time.Duration `json:"offset,omitempty"`
+	Sync    []SyncPoint   `json:"sync,omitempty"`
+
+	// Repair drops watermark lines and empty cues, trims overlapping cues and renumbers the rest
+	Repair bool `json:"repair,omitempty"`
+
+	// Report adds a SubtitleReport of the served file
+	Report bool `json:"report,omitempty"`
+}
+
+// MergeRequest merges a downloaded subtitle with an uploaded one in another language.
+// Format picks the output, "srt" (the default) or "ass".
+type MergeRequest struct {
+	DownloadRequest
+	// Secondary is the uploaded subtitle file shown along with the downloaded one
+	Secondary     []byte `json:"-"`
+	SecondaryName string `json:"secondary_name"`
+}
+
+// SyncPoint moves the subtitle time From to the video time To
+type SyncPoint struct {
+	From time.Duration `json:"from"`
+	To   time.Duration `json:"to"`
 }
 
 type SubtitleFile struct {
 	Filename string `json:"filename"`
 	Content  []byte `json:"content"`
+	// Encoding is the encoding the file was detected in before any conversion
+	Encoding string `json:"encoding,omitempty"`
+	// Report describes the file as served, when the request asks for it
+	Report *SubtitleReport `json:"report,omitempty"`
+}
+
+// SubtitleReport is the result of validating a subtitle file
+type SubtitleReport struct {
+	Filename string `json:"filename"`
+	Format   string `json:"format"`
+	Encoding string `json:"encoding"`
+	Cues     int    `json:"cues"`
+	// Start and End are the first and last times a cue is shown, as hh:mm:ss.mmm
+	Start          string          `json:"start"`
+	End            string          `json:"end"`
+	SinhalaPercent float64         `json:"sinhala_percent"`
+	LegacyFont     bool            `json:"legacy_font,omitempty"`
+	Valid          bool            `json:"valid"`
+	Issues         []SubtitleIssue `json:"issues"`
+}
+
+// SubtitleIssue is a problem found in a subtitle file. Cue is the position of the cue
+// it concerns, counted from 1, and is left out for problems with the whole file.
+type SubtitleIssue struct {
+	Cue     int    `json:"cue,omitempty"`
+	Kind    string `json:"kind"`
+	Message string `json:"message"`
 }
 
 type DownloadResponse struct {
@@ -31,11 +139,66 @@ type DownloadResponse struct {
 	Size     int64  `json:"size"`
 }
 
+// DownloadContentsResponse describes what a download contains
+type DownloadContentsResponse struct {
+	Filename string `json:"filename"`
+	Size     int64  `json:"size"`
+	// Archive is "zip" or "rar", empty when the download is a single subtitle file
+	Archive string        `json:"archive,omitempty"`
+	Files   []ArchiveFile `json:"files"`
+}
+
+// ArchiveFile is a subtitle file inside a download
+type ArchiveFile struct {
+	Name string `json:"name"`
+	Size int64  `json:"size"`
+	// Format is srt, vtt, ass, ssa or microdvd, empty when it is not recognised
+	Format   string `json:"format,omitempty"`
+	Encoding string `json:"encoding"`
+	Cues     int    `json:"cues"`
+}
+
+type EpisodesResponse struct {
+	Episodes []SearchResult `json:"episodes"`
+}
+
 type SourcesResponse struct {
-	Sources []string `json:"sources"`
+	Sources []string       `json:"sources"`
+	Details []SourceStatus `json:"details"`
 }
 
-type SourceCompleteEvent struct {
-	Source string `json:"source"`
-	Count  int    `json:"count"`
+type SourceStatus struct {
+	Name                string     `json:"name"`
+	Status              string     `json:"status"`
+	Available           bool       `json:"available"`
+	LatencyMs           int64      `json:"latency_ms"`
+	LastChecked         *time.Time `json:"last_checked,omitempty"`
+	LastSuccess         *time.Time `json:"last_success,omitempty"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+}
+
+const (
+	SearchStatusOK      = "ok"
+	SearchStatusError   = "error"
+	SearchStatusTimeout = "timeout"
+)
+
+// Cache states of a source search: served from the cache, fetched upstream, or
+// shared with an identical search that was already fetching upstream
+const (
+	CacheHit    = "hit"
+	CacheMiss   = "miss"
+	CacheShared = "shared"
+)
+
+// SourceSearchStatus reports how a single source did during a search
+type SourceSearchStatus struct {
+	Source     string `json:"source"`
+	Status     string `json:"status"`
+	ErrorClass string `json:"error_class,omitempty"`
+	Error      string `json:"error,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+	Count      int    `json:"count"`
+	// Cache is empty when the search cache is disabled
+	Cache string `json:"cache,omitempty"`
 }
@@ -0,0 +1,159 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"ipmanlk/bettercopelk/internal/cache"
+	"ipmanlk/bettercopelk/internal/models"
+	"ipmanlk/bettercopelk/internal/sources"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// searchCache holds recent upstream results per source and query, and coalesces
+// identical searches that run at the same time into one upstream request
+type searchCache struct {
+	store   cache.Store
+	flights cache.Group[[]models.SearchResult]
+}
+
+func (c *searchCache) get(key string) ([]models.SearchResult, bool) {
+	data, _, ok := c.store.Get(key)
+	if !ok {
+		return nil, false
+	}
+
+	var results []models.SearchResult
+	if err := json.Unmarshal(data, &results); err != nil {
+		c.store.Delete(key)
+		return nil, false
+	}
+	return results, true
+}
+
+func (c *searchCache) set(key string, results []models.SearchResult) {
+	data, err := json.Marshal(results)
+	if err == nil {
+		err = c.store.Set(key, data)
+	}
+	if err != nil {
+		log.Printf("Failed to cache search results for %s: %v", key, err)
+	}
+}
+
+// cacheKey identifies the upstream results of a query on a source. Filters are applied
+// after the upstream search, so only the query takes part. The version of a reloadable
+// source is included, so a reloaded definition never serves results cached by the old one.
+func cacheKey(src sources.Source, query string) string {
+	name := src.Name()
+	if versioner, ok := src.(sources.Versioner); ok {
+		name += "@" + versioner.Version()
+	}
+	return name + "|" + strings.Join(strings.Fields(strings.ToLower(query)), " ")
+}
+
+// searchSource searches src and passes its results to onPage as they arrive, going
+// through the search cache when it is enabled. It returns how the cache was used.
+// onPage is never called after searchSource returns.
+func (s *SubtitleService) searchSource(ctx context.Context, src sources.Source, req models.SearchRequest, onPage func([]models.SearchResult)) (string, error) {
+	var mu sync.Mutex
+	returned := false
+	guarded := func(page []models.SearchResult) {
+		mu.Lock()
+		defer mu.Unlock()
+		if !returned {
+			onPage(page)
+		}
+	}
+	defer func() {
+		mu.Lock()
+		returned = true
+		mu.Unlock()
+	}()
+
+	if s.cache == nil {
+		return "", runSearch(ctx, src, req, guarded)
+	}
+
+	key := cacheKey(src, req.Query)
+	if results, ok := s.cache.get(key); ok {
+		guarded(results)
+		return models.CacheHit, nil
+	}
+
+	results, shared, err := s.cache.flights.Do(ctx, key, func() ([]models.SearchResult, error) {
+		// Detached from ctx, so searches that joined this one are not cut short
+		// when the search that started it gives up
+		upstreamCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), s.options.SourceTimeout)
+		defer cancel()
+
+		var all []models.SearchResult
+		err := runSearch(upstreamCtx, src, req, func(page []models.SearchResult) {
+			all = append(all, page...)
+			guarded(page)
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		s.cache.set(key, all)
+		return all, nil
+	})
+
+	if !shared {
+		return models.CacheMiss, err
+	}
+	if err == nil {
+		guarded(results)
+	}
+	return models.CacheShared, err
+}
+
+// runSearch searches src page by page when it supports it, in one go otherwise
+func runSearch(ctx context.Context, src sources.Source, req models.SearchRequest, onPage func([]models.SearchResult)) error {
+	if pager, ok := src.(sources.PageSearcher); ok {
+		return pager.SearchPages(ctx, req, onPage)
+	}
+
+	results, err := src.Search(ctx, req)
+	if err != nil {
+		return err
+	}
+	onPage(results)
+	return nil
+}
+
+// cachedDownload is an archive read from the download cache
+type cachedDownload struct {
+	filename string
+	content  []byte
+	storedAt time.Time
+}
+
+// getDownload reads an archive stored by setDownload. The filename is kept in front of
+// the content, separated by a NUL byte that cannot appear in a filename.
+func getDownload(store cache.Store, key string) (cachedDownload, bool) {
+	data, storedAt, ok := store.Get(key)
+	if !ok {
+		return cachedDownload{}, false
+	}
+
+	filename, content, found := bytes.Cut(data, []byte{0})
+	if !found {
+		store.Delete(key)
+		return cachedDownload{}, false
+	}
+
+	return cachedDownload{filename: string(filename), content: content, storedAt: storedAt}, true
+}
+
+func setDownload(store cache.Store, key, filename string, content []byte) error {
+	data := make([]byte, 0, len(filename)+1+len(content))
+	data = append(data, filename...)
+	data = append(data, 0)
+	data = append(data, content...)
+	return store.Set(key, data)
+}
@@ -0,0 +1,156 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"ipmanlk/bettercopelk/internal/models"
+	"ipmanlk/bettercopelk/internal/sources"
+	"ipmanlk/bettercopelk/internal/titleparser"
+	"time"
+)
+
+var errSearchDeadline = fmt.Errorf("search deadline exceeded: %w", context.DeadlineExceeded)
+
+// sourceOutcome is the result of searching a single source. Results are delivered in
+// partial outcomes, one per results page, before the final outcome with the status.
+type sourceOutcome struct {
+	index   int
+	results []models.SearchResult
+	status  models.SourceSearchStatus
+	partial bool
+}
+
+// enrichResults returns a copy of results with the metadata parsed from each title filled in
+func enrichResults(results []models.SearchResult) []models.SearchResult {
+	if results == nil {
+		return nil
+	}
+
+	enriched := make([]models.SearchResult, len(results))
+	for i, result := range results {
+		meta := titleparser.Parse(result.Title)
+		result.Year = meta.Year
+		result.Season = meta.Season
+		result.Episode = meta.Episode
+		result.Tags = meta.Tags
+		result.Translator = meta.Translator
+		result.Children = enrichResults(result.Children)
+		if result.Type == "" {
+			result.Type = models.TypeMovie
+			if meta.Season > 0 || meta.Episode > 0 {
+				result.Type = models.TypeTV
+			}
+		}
+		enriched[i] = result
+	}
+	return enriched
+}
+
+// fanOut searches every source concurrently and delivers one final outcome per source in
+// completion order, preceded by partial outcomes carrying the results as they arrive.
+// Each source gets SourceTimeout, and once SearchTimeout passes the sources that have not
+// answered yet are reported as timed out without waiting for them, keeping the pages they
+// already delivered. The returned channel is closed when every source has been reported
+// or ctx is cancelled.
+func (s *SubtitleService) fanOut(ctx context.Context, srcs []sources.Source, req models.SearchRequest) <-chan sourceOutcome {
+	out := make(chan sourceOutcome)
+	start := time.Now()
+	searchCtx, cancel := context.WithTimeout(ctx, s.options.SearchTimeout)
+
+	pending := make(chan sourceOutcome, len(srcs))
+
+	// Outcomes are dropped once the search is over, so sources finishing late never block
+	deliver := func(outcome sourceOutcome) bool {
+		select {
+		case pending <- outcome:
+			return true
+		case <-searchCtx.Done():
+			return false
+		}
+	}
+
+	for i, source := range srcs {
+		go func(i int, src sources.Source) {
+			srcCtx, cancelSrc := context.WithTimeout(searchCtx, s.options.SourceTimeout)
+			defer cancelSrc()
+
+			count := 0
+			cacheState, err := s.searchSource(srcCtx, src, req, func(page []models.SearchResult) {
+				page = filterResults(enrichResults(page), req)
+				if len(page) > 0 && deliver(sourceOutcome{index: i, results: page, partial: true}) {
+					count += len(page)
+				}
+			})
+
+			status := newSourceStatus(src.Name(), start, count, err)
+			status.Cache = cacheState
+			deliver(sourceOutcome{index: i, status: status})
+		}(i, source)
+	}
+
+	go func() {
+		defer close(out)
+		defer cancel()
+
+		send := func(outcome sourceOutcome) bool {
+			select {
+			case out <- outcome:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		reported := make([]bool, len(srcs))
+		delivered := make([]int, len(srcs))
+		receive := func(outcome sourceOutcome) bool {
+			if outcome.partial {
+				delivered[outcome.index] += len(outcome.results)
+			} else {
+				reported[outcome.index] = true
+			}
+			return send(outcome)
+		}
+
+		for remaining := len(srcs); remaining > 0; {
+			select {
+			case outcome := <-pending:
+				if !outcome.partial {
+					remaining--
+				}
+				if !receive(outcome) {
+					return
+				}
+
+			case <-searchCtx.Done():
+				// Keep whatever arrived right at the deadline before giving up on the rest
+				for drained := false; !drained; {
+					select {
+					case outcome := <-pending:
+						if !receive(outcome) {
+							return
+						}
+					default:
+						drained = true
+					}
+				}
+
+				for i, src := range srcs {
+					if reported[i] {
+						continue
+					}
+					outcome := sourceOutcome{
+						index:  i,
+						status: newSourceStatus(src.Name(), start, delivered[i], errSearchDeadline),
+					}
+					if !send(outcome) {
+						return
+					}
+				}
+				return
+			}
+		}
+	}()
+
+	return out
+}
@@ -0,0 +1,39 @@
+package services
+
+import "ipmanlk/bettercopelk/internal/models"
+
+// filterResults keeps the results that match the year, type, season and episode filters of req
+func filterResults(results []models.SearchResult, req models.SearchRequest) []models.SearchResult {
+	if req.Year == 0 && req.Type == "" && req.Season == 0 && req.Episode == 0 {
+		return results
+	}
+
+	var filtered []models.SearchResult
+	for _, result := range results {
+		// A collection stays when any of its films match, even if its own title does not
+		children := filterResults(result.Children, req)
+		if matchesFilters(result, req) || len(children) > 0 {
+			result.Children = children
+			filtered = append(filtered, result)
+		}
+	}
+	return filtered
+}
+
+// matchesFilters reports whether result satisfies every filter set on req.
+// Results that do not mention a filtered field (e.g. no year in the title) do not match.
+func matchesFilters(result models.SearchResult, req models.SearchRequest) bool {
+	if req.Year != 0 && result.Year != req.Year {
+		return false
+	}
+	if req.Type != "" && result.Type != req.Type {
+		return false
+	}
+	if req.Season != 0 && result.Season != req.Season {
+		return false
+	}
+	if req.Episode != 0 && result.Episode != req.Episode {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,66 @@
+package services
+
+import (
+	"ipmanlk/bettercopelk/internal/models"
+	"testing"
+)
+
+func TestFilterResults(t *testing.T) {
+	results := enrichResults([]models.SearchResult{
+		{Title: "Loki S02E03 (2023)"},
+		{Title: "Loki S02E04 (2023)"},
+		{Title: "Loki Season 2 (2023)"},
+		{Title: "Loki S01E03 (2021)"},
+		{Title: "Loki (2011)"},
+		{Title: "Loki"},
+	})
+
+	tests := []struct {
+		name string
+		req  models.SearchRequest
+		want []string
+	}{
+		{"no filters", models.SearchRequest{}, []string{"Loki S02E03 (2023)", "Loki S02E04 (2023)", "Loki Season 2 (2023)", "Loki S01E03 (2021)", "Loki (2011)", "Loki"}},
+		{"season and episode", models.SearchRequest{Season: 2, Episode: 3}, []string{"Loki S02E03 (2023)"}},
+		{"season", models.SearchRequest{Season: 2}, []string{"Loki S02E03 (2023)", "Loki S02E04 (2023)", "Loki Season 2 (2023)"}},
+		{"episode", models.SearchRequest{Episode: 3}, []string{"Loki S02E03 (2023)", "Loki S01E03 (2021)"}},
+		{"movies", models.SearchRequest{Type: models.TypeMovie}, []string{"Loki (2011)", "Loki"}},
+		{"tv in year", models.SearchRequest{Type: models.TypeTV, Year: 2021}, []string{"Loki S01E03 (2021)"}},
+		{"year excludes unknown years", models.SearchRequest{Year: 2011}, []string{"Loki (2011)"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, result := range filterResults(results, tt.req) {
+				got = append(got, result.Title)
+			}
+
+			if len(got) != len(tt.want) {
+				t.Fatalf("filterResults() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("filterResults() = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestFilterResults_Collections(t *testing.T) {
+	results := enrichResults([]models.SearchResult{
+		{Title: "Batman Collection", Collection: true, Children: []models.SearchResult{
+			{Title: "Batman (1989)"},
+			{Title: "Batman Returns (1992)"},
+		}},
+		{Title: "Superman Collection", Collection: true, Children: []models.SearchResult{
+			{Title: "Superman (1978)"},
+		}},
+	})
+
+	filtered := filterResults(results, models.SearchRequest{Year: 1992})
+	if len(filtered) != 1 || len(filtered[0].Children) != 1 || filtered[0].Children[0].Title != "Batman Returns (1992)" {
+		t.Errorf("Expected only the Batman collection with its 1992 film, got %+v", filtered)
+	}
+}
@@ -0,0 +1,113 @@
+package services
+
+import (
+	"ipmanlk/bettercopelk/internal/models"
+	"ipmanlk/bettercopelk/internal/titleparser"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Grouper collects search results into works, one per film or series found across sources
+type Grouper struct {
+	works map[string]*models.Work
+	order []string
+}
+
+func NewGrouper() *Grouper {
+	return &Grouper{
+		works: make(map[string]*models.Work),
+	}
+}
+
+// Add puts result into the work it belongs to and returns that work
+func (g *Grouper) Add(result models.SearchResult) models.Work {
+	key := g.keyFor(result)
+
+	work, exists := g.works[key]
+	if !exists {
+		work = &models.Work{
+			Key:  key,
+			Year: resultYear(result),
+		}
+		g.works[key] = work
+		g.order = append(g.order, key)
+	}
+
+	work.Variants = append(work.Variants, result)
+	sort.SliceStable(work.Variants, func(i, j int) bool {
+		return work.Variants[i].Score > work.Variants[j].Score
+	})
+
+	work.Score = work.Variants[0].Score
+	work.Title = cleanTitle(work.Variants[0].Title)
+	if work.Year == 0 {
+		work.Year = resultYear(result)
+	}
+
+	return copyWork(work)
+}
+
+// Works returns all works sorted by their best score, ties keep the order they were first seen in
+func (g *Grouper) Works() []models.Work {
+	works := make([]models.Work, 0, len(g.order))
+	for _, key := range g.order {
+		works = append(works, copyWork(g.works[key]))
+	}
+
+	sort.SliceStable(works, func(i, j int) bool {
+		return works[i].Score > works[j].Score
+	})
+
+	return works
+}
+
+// keyFor identifies the work a title belongs to. Titles with different years are
+// different works (remakes), a title without a year joins the first work with the same name.
+func (g *Grouper) keyFor(result models.SearchResult) string {
+	name := normalizeTitle(result.Title)
+	if name == "" {
+		name = strings.ToLower(strings.TrimSpace(result.Title))
+	}
+	year := resultYear(result)
+
+	if year != 0 {
+		return name + "|" + strconv.Itoa(year)
+	}
+
+	for _, key := range g.order {
+		if strings.HasPrefix(key, name+"|") {
+			return key
+		}
+	}
+	return name + "|"
+}
+
+// GroupResults groups results into works in a single pass
+func GroupResults(results []models.SearchResult) []models.Work {
+	grouper := NewGrouper()
+	for _, result := range results {
+		grouper.Add(result)
+	}
+	return grouper.Works()
+}
+
+// normalizeTitle reduces a title to the lowercase words that identify the work,
+// dropping subtitle boilerplate, punctuation, years, episode markers and release tags
+func normalizeTitle(title string) string {
+	return strings.Join(significantTokens(titleparser.Parse(title).Name), " ")
+}
+
+// cleanTitle strips subtitle boilerplate from a title for display
+func cleanTitle(title string) string {
+	if cleaned := titleparser.StripBoilerplate(title); cleaned != "" {
+		return cleaned
+	}
+	return strings.TrimSpace(title)
+}
+
+func copyWork(work *models.Work) models.Work {
+	cp := *work
+	cp.Variants = append([]models.SearchResult(nil), work.Variants...)
+	return cp
+}
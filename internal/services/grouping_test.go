@@ -0,0 +1,102 @@
+package services
+
+import (
+	"ipmanlk/bettercopelk/internal/models"
+	"testing"
+)
+
+func TestNormalizeTitle(t *testing.T) {
+	tests := []struct {
+		title string
+		want  string
+	}{
+		{"Oppenheimer (2023) Sinhala Subtitles", "oppenheimer"},
+		{"Oppenheimer [2023] සිංහල උපසිරැසි සමඟ", "oppenheimer"},
+		{"Spider-Man: No Way Home (2021) with Sinhala Subtitle", "spider man no way home"},
+		{"The Batman | සිංහල උපසිරැසි", "the batman"},
+		{"The Batman | සිංහල උපසිරසි", "the batman"},
+	}
+
+	for _, tt := range tests {
+		if got := normalizeTitle(tt.title); got != tt.want {
+			t.Errorf("normalizeTitle(%q) = %q, want %q", tt.title, got, tt.want)
+		}
+	}
+}
+
+func TestCleanTitle(t *testing.T) {
+	tests := []struct {
+		title string
+		want  string
+	}{
+		{"Oppenheimer (2023) Sinhala Subtitles", "Oppenheimer (2023)"},
+		{"Oppenheimer (2023) [Sinhala Subtitles]", "Oppenheimer (2023)"},
+		{"The Batman | සිංහල උපසිරැසි සමඟ", "The Batman"},
+		{"Sinhala Subtitles", "Sinhala Subtitles"},
+	}
+
+	for _, tt := range tests {
+		if got := cleanTitle(tt.title); got != tt.want {
+			t.Errorf("cleanTitle(%q) = %q, want %q", tt.title, got, tt.want)
+		}
+	}
+}
+
+func TestGroupResults(t *testing.T) {
+	results := []models.SearchResult{
+		{Title: "Dune (2021) Sinhala Subtitles", Source: "baiscopelk", Score: 0.9},
+		{Title: "Dune: Part Two (2024)", Source: "cineru", Score: 0.5},
+		{Title: "Dune [2021] සිංහල උපසිරැසි සමඟ", Source: "cineru", Score: 0.95},
+		{Title: "Dune (1984)", Source: "zoomlk", Score: 0.6},
+		{Title: "Dune", Source: "piratelk", Score: 0.7},
+	}
+
+	works := GroupResults(results)
+	if len(works) != 3 {
+		t.Fatalf("Expected 3 works, got %d: %+v", len(works), works)
+	}
+
+	dune := works[0]
+	if dune.Year != 2021 || len(dune.Variants) != 3 {
+		t.Fatalf("Expected Dune (2021) with 3 variants first, got %+v", dune)
+	}
+	if dune.Score != 0.95 || dune.Variants[0].Source != "cineru" {
+		t.Errorf("Expected the best variant first, got %+v", dune.Variants)
+	}
+	if dune.Title != "Dune [2021]" {
+		t.Errorf("Work title = %q, want the cleaned title of the best variant", dune.Title)
+	}
+
+	if works[1].Year != 1984 || works[2].Title != "Dune: Part Two (2024)" {
+		t.Errorf("Unexpected work order: %+v", works)
+	}
+}
+
+func TestGroupResults_BothSinhalaSpellings(t *testing.T) {
+	works := GroupResults([]models.SearchResult{
+		{Title: "Dune සිංහල උපසිරසි", Source: "cineru", Score: 0.8},
+		{Title: "Dune Sinhala Subtitles", Source: "baiscopelk", Score: 0.9},
+		{Title: "Dune සිංහල උපසිරැසි සමඟ", Source: "zoomlk", Score: 0.7},
+	})
+
+	if len(works) != 1 || len(works[0].Variants) != 3 {
+		t.Errorf("Expected one work with 3 variants, got %+v", works)
+	}
+}
+
+func TestGrouper_AddReturnsUpdatedWork(t *testing.T) {
+	grouper := NewGrouper()
+
+	first := grouper.Add(models.SearchResult{Title: "Loki (2021)", Source: "a", Score: 0.5})
+	second := grouper.Add(models.SearchResult{Title: "Loki 2021 Sinhala Subtitles", Source: "b", Score: 0.8})
+
+	if first.Key != second.Key {
+		t.Fatalf("Expected both results in the same work, got keys %q and %q", first.Key, second.Key)
+	}
+	if len(first.Variants) != 1 || len(second.Variants) != 2 {
+		t.Errorf("Expected returned works to be snapshots, got %d and %d variants", len(first.Variants), len(second.Variants))
+	}
+	if second.Score != 0.8 {
+		t.Errorf("Work score = %v, want 0.8", second.Score)
+	}
+}
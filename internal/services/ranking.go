@@ -0,0 +1,150 @@
+package services
+
+import (
+	"ipmanlk/bettercopelk/internal/models"
+	"ipmanlk/bettercopelk/internal/titleparser"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const (
+	overlapWeight    = 0.6
+	exactMatchWeight = 0.25
+	yearWeight       = 0.15
+)
+
+var yearPattern = regexp.MustCompile(`\b(19\d{2}|20\d{2})\b`)
+
+// noiseTokens are words sites add to almost every title, they say nothing about the film
+var noiseTokens = map[string]bool{
+	"sinhala":   true,
+	"subtitle":  true,
+	"subtitles": true,
+	"sub":       true,
+	"subs":      true,
+	"with":      true,
+	"සිංහල":     true,
+	"උපසිරැසි":  true,
+	"උපසිරසි":   true,
+	"සමඟ":       true,
+}
+
+// Ranker scores search results against the query they were found for
+type Ranker struct {
+	// weights holds the reliability of each source in (0, 1], missing sources count as 1
+	weights map[string]float64
+}
+
+func NewRanker(weights map[string]float64) *Ranker {
+	return &Ranker{weights: weights}
+}
+
+// Score returns a relevance score in [0, 1] for result. It combines how many query
+// words appear in the title, whether the title is exactly the query, whether the
+// years match, and the reliability of the source.
+func (r *Ranker) Score(query string, result models.SearchResult) float64 {
+	queryTokens := significantTokens(query)
+	titleTokens := significantTokens(result.Title)
+
+	score := overlapWeight*tokenOverlap(queryTokens, titleTokens) +
+		exactMatchWeight*exactMatch(queryTokens, titleTokens) +
+		yearWeight*yearMatch(query, resultYear(result))
+
+	if weight, ok := r.weights[result.Source]; ok {
+		score *= weight
+	}
+
+	return math.Round(score*1000) / 1000
+}
+
+// Rank scores every result and sorts them by descending score. Results with equal
+// scores keep their original order. The children of a collection are ranked too, and
+// the collection scores as well as its best child.
+func (r *Ranker) Rank(query string, results []models.SearchResult) {
+	for i := range results {
+		results[i].Score = r.Score(query, results[i])
+		if len(results[i].Children) > 0 {
+			r.Rank(query, results[i].Children)
+			results[i].Score = math.Max(results[i].Score, results[i].Children[0].Score)
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+}
+
+// tokenize lowercases text and splits it into words. Combining marks are kept so
+// Sinhala words are not broken apart at vowel signs.
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r) && !unicode.IsMark(r)
+	})
+}
+
+// significantTokens returns the words of text without noise words and years
+func significantTokens(text string) []string {
+	var tokens []string
+	for _, token := range tokenize(text) {
+		if noiseTokens[token] || yearPattern.MatchString(token) {
+			continue
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens
+}
+
+// tokenOverlap is the share of query tokens that appear in the title
+func tokenOverlap(queryTokens, titleTokens []string) float64 {
+	if len(queryTokens) == 0 {
+		return 0
+	}
+
+	inTitle := make(map[string]bool, len(titleTokens))
+	for _, token := range titleTokens {
+		inTitle[token] = true
+	}
+
+	matched := 0
+	for _, token := range queryTokens {
+		if inTitle[token] {
+			matched++
+		}
+	}
+
+	return float64(matched) / float64(len(queryTokens))
+}
+
+// exactMatch is 1 when the title has exactly the query words in the same order
+func exactMatch(queryTokens, titleTokens []string) float64 {
+	if len(queryTokens) == 0 || strings.Join(queryTokens, " ") != strings.Join(titleTokens, " ") {
+		return 0
+	}
+	return 1
+}
+
+// yearMatch is 1 when the query names the year of the result, 0.5 when the
+// query names no year, and 0 when the years differ
+func yearMatch(query string, year int) float64 {
+	queryYear := yearPattern.FindString(query)
+	if queryYear == "" {
+		return 0.5
+	}
+
+	if strconv.Itoa(year) == queryYear {
+		return 1
+	}
+	return 0
+}
+
+// resultYear returns the parsed year of a result, parsing the title if it was not enriched yet
+func resultYear(result models.SearchResult) int {
+	if result.Year != 0 {
+		return result.Year
+	}
+	return titleparser.Parse(result.Title).Year
+}
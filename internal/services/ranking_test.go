@@ -0,0 +1,98 @@
+package services
+
+import (
+	"ipmanlk/bettercopelk/internal/models"
+	"math"
+	"testing"
+)
+
+func TestRanker_Score(t *testing.T) {
+	ranker := NewRanker(nil)
+
+	tests := []struct {
+		name   string
+		query  string
+		better string
+		worse  string
+	}{
+		{"exact title beats partial", "the batman", "The Batman (2022) Sinhala Subtitles", "The Batman Returns"},
+		{"more overlap beats less", "batman begins", "Batman Begins", "Batman Forever"},
+		{"matching year beats other year", "dune 2021", "Dune (2021)", "Dune (1984)"},
+		{"sinhala boilerplate is ignored", "oppenheimer", "Oppenheimer සිංහල උපසිරැසි සමඟ", "Oppenheimer Documentary"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			better := ranker.Score(tt.query, models.SearchResult{Title: tt.better})
+			worse := ranker.Score(tt.query, models.SearchResult{Title: tt.worse})
+			if better <= worse {
+				t.Errorf("Score(%q) = %v, want more than Score(%q) = %v", tt.better, better, tt.worse, worse)
+			}
+		})
+	}
+}
+
+func TestRanker_ScoreRange(t *testing.T) {
+	ranker := NewRanker(nil)
+
+	if got := ranker.Score("dune 2021", models.SearchResult{Title: "Dune (2021)"}); got != 1 {
+		t.Errorf("Perfect match score = %v, want 1", got)
+	}
+	if got := ranker.Score("dune 2021", models.SearchResult{Title: "Avatar (2009)"}); got != 0 {
+		t.Errorf("Unrelated title score = %v, want 0", got)
+	}
+}
+
+func TestRanker_SourceWeight(t *testing.T) {
+	ranker := NewRanker(map[string]float64{"flaky": 0.5})
+
+	reliable := ranker.Score("dune", models.SearchResult{Title: "Dune", Source: "reliable"})
+	flaky := ranker.Score("dune", models.SearchResult{Title: "Dune", Source: "flaky"})
+
+	if math.Abs(flaky*2-reliable) > 0.002 {
+		t.Errorf("Weighted score = %v, want half of %v", flaky, reliable)
+	}
+}
+
+func TestRanker_Rank(t *testing.T) {
+	results := []models.SearchResult{
+		{Title: "Batman Forever", Source: "a"},
+		{Title: "Batman Begins (2005)", Source: "b"},
+		{Title: "Superman", Source: "c"},
+		{Title: "Batman Begins", Source: "d"},
+	}
+
+	NewRanker(nil).Rank("batman begins 2005", results)
+
+	expected := []string{"b", "d", "a", "c"}
+	for i, source := range expected {
+		if results[i].Source != source {
+			t.Fatalf("Rank() order = %+v, want sources %v", results, expected)
+		}
+	}
+
+	for i := 1; i < len(results); i++ {
+		if results[i].Score > results[i-1].Score {
+			t.Errorf("Results not sorted by score: %+v", results)
+		}
+	}
+}
+
+func TestRanker_RankCollections(t *testing.T) {
+	results := []models.SearchResult{
+		{Title: "Batman Forever", Source: "a"},
+		{Title: "Batman Collection", Source: "b", Collection: true, Children: []models.SearchResult{
+			{Title: "Batman (1989)"},
+			{Title: "Batman Begins (2005)"},
+		}},
+	}
+
+	NewRanker(nil).Rank("batman begins", results)
+
+	if results[0].Source != "b" {
+		t.Fatalf("Expected the collection holding the film first, got %+v", results)
+	}
+	if results[0].Children[0].Title != "Batman Begins (2005)" || results[0].Score != results[0].Children[0].Score {
+		t.Errorf("Expected the collection to rank and score as its best child, got %+v", results[0])
+	}
+}
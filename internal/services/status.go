@@ -0,0 +1,60 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"ipmanlk/bettercopelk/internal/models"
+	"ipmanlk/bettercopelk/internal/sources"
+	"net"
+	"time"
+)
+
+const (
+	ErrorClassTimeout    = "timeout"
+	ErrorClassCanceled   = "canceled"
+	ErrorClassNetwork    = "network"
+	ErrorClassHTTPStatus = "http_status"
+	ErrorClassOther      = "other"
+)
+
+// newSourceStatus builds the per-source report for a finished search
+func newSourceStatus(sourceName string, start time.Time, count int, err error) models.SourceSearchStatus {
+	status := models.SourceSearchStatus{
+		Source:     sourceName,
+		Status:     models.SearchStatusOK,
+		DurationMs: time.Since(start).Milliseconds(),
+		Count:      count,
+	}
+
+	if err != nil {
+		status.ErrorClass = classifyError(err)
+		status.Error = err.Error()
+		status.Status = models.SearchStatusError
+		if status.ErrorClass == ErrorClassTimeout {
+			status.Status = models.SearchStatusTimeout
+		}
+	}
+
+	return status
+}
+
+func classifyError(err error) string {
+	var statusErr *sources.StatusError
+	var netErr net.Error
+
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return ErrorClassTimeout
+	case errors.Is(err, context.Canceled):
+		return ErrorClassCanceled
+	case errors.As(err, &statusErr):
+		return ErrorClassHTTPStatus
+	case errors.As(err, &netErr):
+		if netErr.Timeout() {
+			return ErrorClassTimeout
+		}
+		return ErrorClassNetwork
+	default:
+		return ErrorClassOther
+	}
+}
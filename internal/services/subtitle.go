@@ -3,68 +3,111 @@ package services
 import (
 	"context"
 	"fmt"
+	"ipmanlk/bettercopelk/internal/archive"
+	"ipmanlk/bettercopelk/internal/cache"
+	"ipmanlk/bettercopelk/internal/charset"
 	"ipmanlk/bettercopelk/internal/models"
+	"ipmanlk/bettercopelk/internal/sinhala"
 	"ipmanlk/bettercopelk/internal/sources"
-	"sync"
+	"ipmanlk/bettercopelk/internal/subtitle"
+	"log"
+	"path"
+	"strings"
+	"time"
 )
 
+const (
+	DefaultSourceTimeout = 15 * time.Second
+	DefaultSearchTimeout = 20 * time.Second
+	DefaultDownloadTTL   = 24 * time.Hour
+)
+
+type Options struct {
+	// SourceTimeout bounds the search of a single source
+	SourceTimeout time.Duration
+	// SearchTimeout bounds the whole search, results that arrive later are dropped
+	SearchTimeout time.Duration
+	// SearchCache keeps upstream search results by source and query until the store
+	// expires them, nil disables the search cache
+	SearchCache cache.Store
+	// Downloads caches downloaded archives by source and post URL, nil disables it
+	Downloads cache.Store
+	// DownloadTTL is how long a cached archive is served without asking the site again.
+	// Older archives are still served when the site fails, until Downloads drops them.
+	DownloadTTL time.Duration
+}
+
 type SubtitleService struct {
 	sourceManager *sources.Manager
+	healthMonitor *sources.HealthMonitor
+	options       Options
+	cache         *searchCache
 }
 
-func NewSubtitleService(sourceManager *sources.Manager) *SubtitleService {
-	return &SubtitleService{
+func NewSubtitleService(sourceManager *sources.Manager, healthMonitor *sources.HealthMonitor, options Options) *SubtitleService {
+	if options.SourceTimeout <= 0 {
+		options.SourceTimeout = DefaultSourceTimeout
+	}
+	if options.SearchTimeout <= 0 {
+		options.SearchTimeout = DefaultSearchTimeout
+	}
+	if options.DownloadTTL <= 0 {
+		options.DownloadTTL = DefaultDownloadTTL
+	}
+
+	service := &SubtitleService{
 		sourceManager: sourceManager,
+		healthMonitor: healthMonitor,
+		options:       options,
+	}
+
+	if options.SearchCache != nil {
+		service.cache = &searchCache{store: options.SearchCache}
 	}
+
+	return service
 }
 
 func (s *SubtitleService) Search(ctx context.Context, req models.SearchRequest) (*models.SearchResponse, error) {
-	var allResults []models.SearchResult
-	var wg sync.WaitGroup
-	var mu sync.Mutex
+	sourcesToSearch := s.resolveSources(req.Sources)
+	if len(req.Sources) > 0 && len(sourcesToSearch) == 0 {
+		return nil, fmt.Errorf("none of the requested sources are available")
+	}
 
-	sourcesToSearch := make(map[string]sources.Source)
+	// Outcomes are stored by source index, so results keep the registry order of their source
+	sourceResults := make([][]models.SearchResult, len(sourcesToSearch))
+	statuses := make([]models.SourceSearchStatus, len(sourcesToSearch))
 
-	if len(req.Sources) > 0 {
-		for _, sourceName := range req.Sources {
-			source, exists := s.sourceManager.GetSource(sourceName)
-			if !exists {
-				continue
-			}
-
-			sourcesToSearch[sourceName] = source
+	for outcome := range s.fanOut(ctx, sourcesToSearch, req) {
+		sourceResults[outcome.index] = append(sourceResults[outcome.index], outcome.results...)
+		if !outcome.partial {
+			statuses[outcome.index] = outcome.status
 		}
+	}
 
-		if len(sourcesToSearch) == 0 {
-			return nil, fmt.Errorf("none of the requested sources are available")
-		}
-	} else {
-		for name, source := range s.sourceManager.GetAllSources() {
-			sourcesToSearch[name] = source
-		}
+	if err := ctx.Err(); err != nil {
+		return nil, err
 	}
 
-	for name, source := range sourcesToSearch {
-		wg.Add(1)
-		go func(src sources.Source, srcName string) {
-			defer wg.Done()
+	// Results is always sent, as an empty list when nothing was found
+	allResults := []models.SearchResult{}
+	for _, results := range sourceResults {
+		allResults = append(allResults, results...)
+	}
 
-			results, err := src.Search(ctx, req)
-			if err != nil {
-				fmt.Printf("Search failed for source %s: %v\n", srcName, err)
-				return
-			}
+	NewRanker(sourceWeights(sourcesToSearch)).Rank(req.Query, allResults)
 
-			mu.Lock()
-			allResults = append(allResults, results...)
-			mu.Unlock()
-		}(source, name)
+	if req.Grouped {
+		return &models.SearchResponse{
+			Results: []models.SearchResult{},
+			Works:   GroupResults(allResults),
+			Sources: statuses,
+		}, nil
 	}
 
-	wg.Wait()
-
 	return &models.SearchResponse{
 		Results: allResults,
+		Sources: statuses,
 	}, nil
 }
 
@@ -75,92 +118,451 @@ func (s *SubtitleService) Download(ctx context.Context, req models.DownloadReque
 		return nil, "", fmt.Errorf("source '%s' not found", req.Source)
 	}
 
+	if s.options.Downloads == nil {
+		content, filename, err := source.Download(ctx, req.URL)
+		if err != nil {
+			return nil, "", fmt.Errorf("download failed for source %s: %w", req.Source, err)
+		}
+		return content, filename, nil
+	}
+
+	key := req.Source + "|" + req.URL
+	cached, isCached := getDownload(s.options.Downloads, key)
+	if isCached && time.Since(cached.storedAt) < s.options.DownloadTTL {
+		return cached.content, cached.filename, nil
+	}
+
 	content, filename, err := source.Download(ctx, req.URL)
 	if err != nil {
+		if isCached {
+			log.Printf("Serving cached download of %s from %s: %v", req.URL, req.Source, err)
+			return cached.content, cached.filename, nil
+		}
 		return nil, "", fmt.Errorf("download failed for source %s: %w", req.Source, err)
 	}
 
+	if err := setDownload(s.options.Downloads, key, filename, content); err != nil {
+		log.Printf("Failed to cache download of %s from %s: %v", req.URL, req.Source, err)
+	}
+
 	return content, filename, nil
 }
 
-func (s *SubtitleService) GetAvailableSources() []string {
-	return s.sourceManager.GetAvailableSources()
+// DownloadFile returns the subtitle file req.File from the archive of a post. Posts that
+// link a subtitle file rather than an archive serve it when the name matches or no file
+// is named. The file is converted, retimed and re-encoded as the request asks.
+func (s *SubtitleService) DownloadFile(ctx context.Context, req models.DownloadRequest) (*models.SubtitleFile, error) {
+	content, filename, err := s.Download(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var data []byte
+	if archive.Detect(content) == "" {
+		if !archive.IsSubtitle(filename) || (req.File != "" && req.File != filename) {
+			return nil, fmt.Errorf("%s: %w", filename, archive.ErrUnsupported)
+		}
+		data = content
+	} else {
+		if req.File == "" {
+			return nil, fmt.Errorf("%s is an archive, name a file in it: %w", filename, archive.ErrUnsupported)
+		}
+		data, err = archive.Extract(content, req.File)
+		if err != nil {
+			return nil, err
+		}
+		filename = path.Base(req.File)
+	}
+
+	file := &models.SubtitleFile{
+		Filename: filename,
+		Content:  data,
+		Encoding: charset.Detect(data),
+	}
+
+	if req.Convert == models.ConvertUnicode {
+		file.Content = convertToUnicode(filename, file.Content)
+	}
+
+	if req.Format != "" || req.Repair || retimes(req) {
+		file.Content, file.Filename, err = rewriteSubtitle(file.Filename, file.Content, req, s.watermarks(req.Source))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	switch req.Encoding {
+	case charset.UTF8:
+		file.Content, _ = charset.ToUTF8(file.Content)
+	case charset.UTF8BOM:
+		text, _ := charset.ToUTF8(file.Content)
+		file.Content = charset.WithBOM(text)
+	}
+
+	// The report is extra, a file it cannot describe is still served without one
+	if req.Report {
+		if file.Report, err = validateFile(file.Filename, file.Content); err != nil {
+			log.Printf("Failed to report on %s from %s: %v", file.Filename, req.URL, err)
+		}
+	}
+
+	return file, nil
 }
-func (s *SubtitleService) ValidateSources(sources []string) error {
-	if len(sources) == 0 {
-		return nil
+
+// Validate downloads a subtitle file like DownloadFile and reports on its quality
+func (s *SubtitleService) Validate(ctx context.Context, req models.DownloadRequest) (*models.SubtitleReport, error) {
+	req.Report = false
+	file, err := s.DownloadFile(ctx, req)
+	if err != nil {
+		return nil, err
 	}
+	return validateFile(file.Filename, file.Content)
+}
 
-	for _, sourceName := range sources {
-		_, exists := s.sourceManager.GetSource(sourceName)
-		if !exists {
-			return fmt.Errorf("invalid source: %s", sourceName)
+// validateFile reports the cues, time span, Sinhala text and issues of a subtitle file.
+// Files in an encoding other than UTF-8 or typed in a legacy Sinhala font are flagged too.
+func validateFile(name string, data []byte) (*models.SubtitleReport, error) {
+	text, encoding := charset.ToUTF8(data)
+	format := subtitle.DetectFormat(name, text)
+	if format == "" {
+		return nil, fmt.Errorf("%s: %w", name, subtitle.ErrUnknownFormat)
+	}
+	parsed, err := subtitle.Parse(format, text)
+	if err != nil {
+		return nil, err
+	}
+
+	start, end := parsed.Span()
+	report := &models.SubtitleReport{
+		Filename:       name,
+		Format:         format,
+		Encoding:       encoding,
+		Cues:           len(parsed.Cues),
+		Start:          subtitle.FormatTimestamp(start),
+		End:            subtitle.FormatTimestamp(end),
+		SinhalaPercent: parsed.SinhalaPercent(),
+		LegacyFont:     sinhala.IsLegacy(subtitle.Text(format, text)),
+		Issues:         []models.SubtitleIssue{},
+	}
+
+	if report.Cues == 0 {
+		report.Issues = append(report.Issues, models.SubtitleIssue{Kind: subtitle.IssueNoCues, Message: "no cues could be read from the file"})
+	}
+	if encoding != charset.UTF8 && encoding != charset.UTF8BOM {
+		report.Issues = append(report.Issues, models.SubtitleIssue{Kind: subtitle.IssueEncoding, Message: "file is " + encoding + ", not UTF-8"})
+	}
+	if report.LegacyFont {
+		report.Issues = append(report.Issues, models.SubtitleIssue{Kind: subtitle.IssueLegacyFont, Message: "text is typed in a legacy Sinhala font, convert it to Unicode"})
+	}
+	for _, issue := range subtitle.Validate(parsed) {
+		report.Issues = append(report.Issues, models.SubtitleIssue{Cue: issue.Cue, Kind: issue.Kind, Message: issue.Message})
+	}
+
+	report.Valid = len(report.Issues) == 0
+	return report, nil
+}
+
+// convertToUnicode converts the cue text of a subtitle file typed in a legacy Sinhala font
+// to Unicode. Files that are not in a legacy font are returned as UTF-8 unchanged.
+func convertToUnicode(name string, data []byte) []byte {
+	text, _ := charset.ToUTF8(data)
+	format := subtitle.DetectFormat(name, text)
+	if format == "" || !sinhala.IsLegacy(subtitle.Text(format, text)) {
+		return text
+	}
+	return subtitle.MapText(format, text, sinhala.ToUnicode)
+}
+
+// DownloadContents downloads a post like Download and describes the subtitle files in it
+func (s *SubtitleService) DownloadContents(ctx context.Context, req models.DownloadRequest) (*models.DownloadContentsResponse, error) {
+	content, filename, err := s.Download(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &models.DownloadContentsResponse{
+		Filename: filename,
+		Size:     int64(len(content)),
+		Archive:  archive.Detect(content),
+		Files:    []models.ArchiveFile{},
+	}
+
+	if response.Archive == "" {
+		if !archive.IsSubtitle(filename) {
+			return nil, fmt.Errorf("%s: %w", filename, archive.ErrUnsupported)
 		}
+		response.Files = append(response.Files, describeFile(filename, content))
+		return response, nil
+	}
+
+	entries, err := archive.ExtractAll(content)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		response.Files = append(response.Files, describeFile(entry.Name, entry.Data))
 	}
 
+	return response, nil
+}
+
+// retimes reports whether req adjusts the timings of the subtitle
+func retimes(req models.DownloadRequest) bool {
+	return req.Offset != 0 || req.FromFPS != 0 || req.ToFPS != 0 || len(req.Sync) > 0
+}
+
+// watermarks returns the watermark patterns of a source, if it has any
+func (s *SubtitleService) watermarks(name string) []string {
+	source, exists := s.sourceManager.GetSource(name)
+	if !exists {
+		return nil
+	}
+	if watermarker, ok := source.(sources.Watermarker); ok {
+		return watermarker.WatermarkPatterns()
+	}
 	return nil
 }
 
-func (s *SubtitleService) StreamSearch(ctx context.Context, req models.SearchRequest, resultChan chan<- models.SearchResult, sourceCompleteChan chan<- models.SourceCompleteEvent) {
-	wg := sync.WaitGroup{}
+// rewriteSubtitle parses a subtitle file, repairs it with the given watermarks when req asks
+// for it, applies the timing adjustments of req and writes it as UTF-8 in req.Format, or
+// its own format when none is asked for
+func rewriteSubtitle(name string, data []byte, req models.DownloadRequest, watermarks []string) ([]byte, string, error) {
+	text, _ := charset.ToUTF8(data)
+	parsed, from, err := parseSubtitle(name, text)
+	if err != nil {
+		return nil, "", err
+	}
+
+	format := req.Format
+	if format == "" {
+		format = from
+	}
+	name = strings.TrimSuffix(name, path.Ext(name)) + subtitle.Extension(format)
+	if from == format && !req.Repair && !retimes(req) {
+		return text, name, nil
+	}
+
+	if req.Repair {
+		parsed.Repair(watermarks)
+	}
+
+	if req.FromFPS != 0 || req.ToFPS != 0 {
+		if err := parsed.ChangeFrameRate(req.FromFPS, req.ToFPS); err != nil {
+			return nil, "", fmt.Errorf("frame rate %v to %v: %w", req.FromFPS, req.ToFPS, err)
+		}
+	}
+	parsed.Shift(req.Offset)
+	if len(req.Sync) > 0 {
+		if len(req.Sync) != 2 {
+			return nil, "", fmt.Errorf("sync needs two points, got %d: %w", len(req.Sync), subtitle.ErrInvalidTiming)
+		}
+		if err := parsed.Sync(req.Sync[0].From, req.Sync[0].To, req.Sync[1].From, req.Sync[1].To); err != nil {
+			return nil, "", err
+		}
+	}
+
+	converted, err := subtitle.Write(parsed, format)
+	if err != nil {
+		return nil, "", err
+	}
+	return converted, name, nil
+}
+
+// parseSubtitle parses a UTF-8 subtitle file in the format it is detected in, returning
+// the format too. Files without cues are not recognised.
+func parseSubtitle(name string, text []byte) (*subtitle.Subtitle, string, error) {
+	format := subtitle.DetectFormat(name, text)
+	if format == "" {
+		return nil, "", fmt.Errorf("%s: %w", name, subtitle.ErrUnknownFormat)
+	}
 
-	sourcesToSearch := make(map[string]sources.Source)
+	parsed, err := subtitle.Parse(format, text)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(parsed.Cues) == 0 {
+		return nil, "", fmt.Errorf("%s has no cues: %w", name, subtitle.ErrUnknownFormat)
+	}
+	return parsed, format, nil
+}
 
-	if len(req.Sources) > 0 {
-		for _, sourceName := range req.Sources {
-			source, exists := s.sourceManager.GetSource(sourceName)
-			if !exists {
-				continue
-			}
-			sourcesToSearch[sourceName] = source
+// Merge downloads a subtitle file like DownloadFile and merges the uploaded secondary
+// subtitle into it. SRT output joins overlapping cues, ASS output shows the downloaded
+// subtitle at the bottom and the secondary one at the top.
+func (s *SubtitleService) Merge(ctx context.Context, req models.MergeRequest) (*models.SubtitleFile, error) {
+	format := req.Format
+	if format == "" {
+		format = subtitle.FormatSRT
+	}
+	if format != subtitle.FormatSRT && format != subtitle.FormatASS {
+		return nil, fmt.Errorf("merging to %q: %w", format, subtitle.ErrUnknownFormat)
+	}
+
+	download := req.DownloadRequest
+	download.Format, download.Encoding = "", ""
+	file, err := s.DownloadFile(ctx, download)
+	if err != nil {
+		return nil, err
+	}
+
+	primaryText, _ := charset.ToUTF8(file.Content)
+	primary, _, err := parseSubtitle(file.Filename, primaryText)
+	if err != nil {
+		return nil, err
+	}
+	secondaryText, _ := charset.ToUTF8(req.Secondary)
+	secondary, _, err := parseSubtitle(req.SecondaryName, secondaryText)
+	if err != nil {
+		return nil, err
+	}
+
+	var merged *subtitle.Subtitle
+	if format == subtitle.FormatASS {
+		merged = subtitle.Stack(primary, secondary)
+	} else {
+		merged = subtitle.Merge(primary, secondary)
+	}
+
+	content, err := subtitle.Write(merged, format)
+	if err != nil {
+		return nil, err
+	}
+	if req.Encoding == charset.UTF8BOM {
+		content = charset.WithBOM(content)
+	}
+
+	return &models.SubtitleFile{
+		Filename: strings.TrimSuffix(file.Filename, path.Ext(file.Filename)) + ".merged" + subtitle.Extension(format),
+		Content:  content,
+		Encoding: file.Encoding,
+	}, nil
+}
+
+// describeFile detects the format and encoding of a subtitle file and counts its cues
+func describeFile(name string, data []byte) models.ArchiveFile {
+	text, encoding := charset.ToUTF8(data)
+	format := subtitle.DetectFormat(name, text)
+	return models.ArchiveFile{
+		Name:     name,
+		Size:     int64(len(data)),
+		Format:   format,
+		Encoding: encoding,
+		Cues:     subtitle.CountCues(format, text),
+	}
+}
+
+// ListEpisodes returns the individual downloads on a series post, numbered by episode
+func (s *SubtitleService) ListEpisodes(ctx context.Context, req models.DownloadRequest) ([]models.SearchResult, error) {
+	source, exists := s.sourceManager.GetSource(req.Source)
+	if !exists {
+		return nil, fmt.Errorf("source '%s' not found", req.Source)
+	}
+
+	lister, ok := source.(sources.ItemLister)
+	if !ok {
+		return nil, fmt.Errorf("source '%s' does not list episodes", req.Source)
+	}
+
+	items, err := lister.ListItems(ctx, req.URL)
+	if err != nil {
+		return nil, fmt.Errorf("listing episodes failed for source %s: %w", req.Source, err)
+	}
+
+	episodes := enrichResults(items)
+	for i := range episodes {
+		episodes[i].Type = models.TypeTV
+		if episodes[i].Episode == 0 {
+			episodes[i].Episode = i + 1
 		}
+	}
+
+	return episodes, nil
+}
 
-		if len(sourcesToSearch) == 0 {
-			close(resultChan)
-			close(sourceCompleteChan)
-			return
+// resolveSources snapshots the sources to search in registry order. When names is
+// empty every registered source is used, unknown names are skipped.
+func (s *SubtitleService) resolveSources(names []string) []sources.Source {
+	all := s.sourceManager.GetAllSources()
+	if len(names) == 0 {
+		return all
+	}
+
+	requested := make(map[string]bool, len(names))
+	for _, name := range names {
+		requested[name] = true
+	}
+
+	var selected []sources.Source
+	for _, source := range all {
+		if requested[source.Name()] {
+			selected = append(selected, source)
 		}
-	} else {
-		for name, source := range s.sourceManager.GetAllSources() {
-			sourcesToSearch[name] = source
+	}
+	return selected
+}
+
+// sourceWeights collects the reliability weights of the sources that declare one
+func sourceWeights(srcs []sources.Source) map[string]float64 {
+	weights := make(map[string]float64)
+	for _, source := range srcs {
+		if w, ok := source.(sources.Weighter); ok {
+			weights[source.Name()] = w.Weight()
 		}
 	}
+	return weights
+}
 
-	for name, source := range sourcesToSearch {
-		wg.Add(1)
-		go func(src sources.Source, srcName string) {
-			defer wg.Done()
+func (s *SubtitleService) GetAvailableSources() []string {
+	return s.healthMonitor.AvailableSources()
+}
 
-			results, err := src.Search(ctx, req)
-			if err != nil {
-				fmt.Printf("Search failed for source %s: %v\n", srcName, err)
-				sourceCompleteChan <- models.SourceCompleteEvent{
-					Source: srcName,
-					Count:  0,
-				}
-				return
-			}
+func (s *SubtitleService) GetSourceStatuses() []models.SourceStatus {
+	return s.healthMonitor.Snapshot()
+}
 
-			count := 0
-			for _, result := range results {
+func (s *SubtitleService) ValidateSources(sources []string) error {
+	if len(sources) == 0 {
+		return nil
+	}
+
+	for _, sourceName := range sources {
+		_, exists := s.sourceManager.GetSource(sourceName)
+		if !exists {
+			return fmt.Errorf("invalid source: %s", sourceName)
+		}
+	}
+
+	return nil
+}
+
+func (s *SubtitleService) StreamSearch(ctx context.Context, req models.SearchRequest, resultChan chan<- models.SearchResult, sourceCompleteChan chan<- models.SourceSearchStatus) {
+	sourcesToSearch := s.resolveSources(req.Sources)
+	ranker := NewRanker(sourceWeights(sourcesToSearch))
+	outcomes := s.fanOut(ctx, sourcesToSearch, req)
+
+	go func() {
+		defer close(resultChan)
+		defer close(sourceCompleteChan)
+
+		for outcome := range outcomes {
+			ranker.Rank(req.Query, outcome.results)
+			for _, result := range outcome.results {
 				select {
 				case <-ctx.Done():
 					return
 				case resultChan <- result:
-					count++
 				}
 			}
 
-			sourceCompleteChan <- models.SourceCompleteEvent{
-				Source: srcName,
-				Count:  count,
+			if outcome.partial {
+				continue
 			}
-		}(source, name)
-	}
 
-	go func() {
-		wg.Wait()
-		close(resultChan)
-		close(sourceCompleteChan)
+			select {
+			case <-ctx.Done():
+				return
+			case sourceCompleteChan <- outcome.status:
+			}
+		}
 	}()
 }
@@ -0,0 +1,900 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"ipmanlk/bettercopelk/internal/archive"
+	"ipmanlk/bettercopelk/internal/cache"
+	"ipmanlk/bettercopelk/internal/charset"
+	"ipmanlk/bettercopelk/internal/models"
+	"ipmanlk/bettercopelk/internal/sources"
+	"ipmanlk/bettercopelk/internal/subtitle"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type fakeSource struct {
+	name     string
+	priority int
+	results  []models.SearchResult
+	err      error
+	delay    time.Duration
+	// hang blocks Search until the channel is closed, ignoring the context
+	hang chan struct{}
+}
+
+func (f *fakeSource) Name() string { return f.name }
+
+func (f *fakeSource) Priority() int { return f.priority }
+
+func (f *fakeSource) Search(ctx context.Context, req models.SearchRequest) ([]models.SearchResult, error) {
+	if f.hang != nil {
+		<-f.hang
+	}
+	if f.delay > 0 {
+		select {
+		case <-time.After(f.delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	return f.results, f.err
+}
+
+func (f *fakeSource) Download(ctx context.Context, url string) ([]byte, string, error) {
+	return []byte("content"), f.name + ".zip", nil
+}
+
+func (f *fakeSource) IsAvailable() bool { return true }
+
+func newFakeSource(name string, priority int, titles ...string) *fakeSource {
+	source := &fakeSource{name: name, priority: priority}
+	for _, title := range titles {
+		source.results = append(source.results, models.SearchResult{
+			Title:  title,
+			URL:    "https://" + name + "/" + title,
+			Source: name,
+		})
+	}
+	return source
+}
+
+// fakeSeriesSource lists its results as the items of every post
+type fakeSeriesSource struct {
+	*fakeSource
+}
+
+func (f *fakeSeriesSource) ListItems(ctx context.Context, postURL string) ([]models.SearchResult, error) {
+	return f.results, f.err
+}
+
+// fakePagedSource delivers each of its pages separately, then hangs until ctx is done
+// when hang is set
+type fakePagedSource struct {
+	*fakeSource
+	pages [][]models.SearchResult
+}
+
+func (f *fakePagedSource) SearchPages(ctx context.Context, req models.SearchRequest, onPage func([]models.SearchResult)) error {
+	for _, page := range f.pages {
+		onPage(page)
+	}
+	if f.hang != nil {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	return nil
+}
+
+func newFakePagedSource(name string, pages ...[]string) *fakePagedSource {
+	source := &fakePagedSource{fakeSource: newFakeSource(name, 0)}
+	for _, titles := range pages {
+		source.pages = append(source.pages, newFakeSource(name, 0, titles...).results)
+	}
+	return source
+}
+
+func newTestService(srcs ...sources.Source) (*SubtitleService, *sources.Manager) {
+	return newTestServiceWithOptions(Options{}, srcs...)
+}
+
+func newTestServiceWithOptions(options Options, srcs ...sources.Source) (*SubtitleService, *sources.Manager) {
+	manager := sources.NewManager()
+	for _, source := range srcs {
+		manager.RegisterSource(source)
+	}
+	return NewSubtitleService(manager, sources.NewHealthMonitor(manager, time.Minute), options), manager
+}
+
+func TestSubtitleService_SearchOrdersResultsBySourcePriority(t *testing.T) {
+	slow := newFakeSource("slow", 1, "first")
+	slow.delay = 20 * time.Millisecond
+	service, _ := newTestService(
+		newFakeSource("fast", 2, "second", "third"),
+		slow,
+	)
+
+	for i := 0; i < 3; i++ {
+		response, err := service.Search(context.Background(), models.SearchRequest{Query: "q"})
+		if err != nil {
+			t.Fatalf("Search failed: %v", err)
+		}
+
+		var titles []string
+		for _, result := range response.Results {
+			titles = append(titles, result.Title)
+		}
+		if fmt.Sprint(titles) != "[first second third]" {
+			t.Fatalf("Search() titles = %v, want [first second third]", titles)
+		}
+	}
+}
+
+func TestSubtitleService_SearchParsesTitleMetadata(t *testing.T) {
+	service, _ := newTestService(newFakeSource("a", 0, "Loki S02E03 (2023) WEB-DL 1080p"))
+
+	response, err := service.Search(context.Background(), models.SearchRequest{Query: "loki"})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+
+	result := response.Results[0]
+	if result.Year != 2023 || result.Season != 2 || result.Episode != 3 || fmt.Sprint(result.Tags) != "[WEB-DL 1080p]" {
+		t.Errorf("Expected parsed metadata, got %+v", result)
+	}
+}
+
+func TestSubtitleService_SearchUnknownSources(t *testing.T) {
+	service, _ := newTestService(newFakeSource("a", 0, "x"))
+
+	if _, err := service.Search(context.Background(), models.SearchRequest{Query: "q", Sources: []string{"missing"}}); err == nil {
+		t.Error("Expected error when none of the requested sources exist")
+	}
+}
+
+func TestSubtitleService_SearchSendsEmptyResults(t *testing.T) {
+	service, _ := newTestService(newFakeSource("empty", 0))
+
+	for _, grouped := range []bool{false, true} {
+		response, err := service.Search(context.Background(), models.SearchRequest{Query: "q", Grouped: grouped})
+		if err != nil {
+			t.Fatalf("Search failed: %v", err)
+		}
+		data, _ := json.Marshal(response)
+		if !strings.Contains(string(data), `"results":[]`) {
+			t.Errorf("Search(grouped=%v) JSON = %s, want an empty results list", grouped, data)
+		}
+	}
+}
+
+func TestSubtitleService_SearchReportsSourceStatus(t *testing.T) {
+	broken := newFakeSource("broken", 1)
+	broken.err = fmt.Errorf("fetch failed: %w", &sources.StatusError{StatusCode: 503})
+	slow := newFakeSource("slow", 2, "late")
+	slow.delay = time.Second
+
+	service, _ := newTestServiceWithOptions(Options{SourceTimeout: 50 * time.Millisecond},
+		newFakeSource("ok", 0, "a", "b"), broken, slow)
+
+	response, err := service.Search(context.Background(), models.SearchRequest{Query: "q"})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+
+	if len(response.Results) != 2 {
+		t.Errorf("Expected 2 results, got %d", len(response.Results))
+	}
+
+	expected := []struct {
+		source, status, class string
+		count                 int
+	}{
+		{"ok", models.SearchStatusOK, "", 2},
+		{"broken", models.SearchStatusError, ErrorClassHTTPStatus, 0},
+		{"slow", models.SearchStatusTimeout, ErrorClassTimeout, 0},
+	}
+
+	if len(response.Sources) != len(expected) {
+		t.Fatalf("Expected %d source statuses, got %d", len(expected), len(response.Sources))
+	}
+
+	for i, want := range expected {
+		got := response.Sources[i]
+		if got.Source != want.source || got.Status != want.status || got.ErrorClass != want.class || got.Count != want.count {
+			t.Errorf("Source status %d = %+v, want %+v", i, got, want)
+		}
+		if want.status != models.SearchStatusOK && got.Error == "" {
+			t.Errorf("Expected error message for %s", got.Source)
+		}
+	}
+}
+
+func TestSubtitleService_SearchDeadlineReturnsPartialResults(t *testing.T) {
+	stuck := newFakeSource("stuck", 1, "never")
+	stuck.hang = make(chan struct{})
+	defer close(stuck.hang)
+
+	service, _ := newTestServiceWithOptions(Options{SearchTimeout: 50 * time.Millisecond},
+		newFakeSource("ok", 0, "a"), stuck)
+
+	start := time.Now()
+	response, err := service.Search(context.Background(), models.SearchRequest{Query: "q"})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Search took %v, expected it to stop at the search deadline", elapsed)
+	}
+
+	if len(response.Results) != 1 || response.Results[0].Title != "a" {
+		t.Errorf("Expected only the fast source's result, got %+v", response.Results)
+	}
+	if response.Sources[1].Source != "stuck" || response.Sources[1].Status != models.SearchStatusTimeout {
+		t.Errorf("Expected stuck source to be timed out, got %+v", response.Sources[1])
+	}
+}
+
+func TestSubtitleService_StreamSearchDeadline(t *testing.T) {
+	stuck := newFakeSource("stuck", 1, "never")
+	stuck.hang = make(chan struct{})
+	defer close(stuck.hang)
+
+	service, _ := newTestServiceWithOptions(Options{SearchTimeout: 50 * time.Millisecond},
+		newFakeSource("ok", 0, "a", "b"), stuck)
+
+	resultChan := make(chan models.SearchResult, 10)
+	sourceCompleteChan := make(chan models.SourceSearchStatus, 10)
+	service.StreamSearch(context.Background(), models.SearchRequest{Query: "q"}, resultChan, sourceCompleteChan)
+
+	results, events := drainStream(resultChan, sourceCompleteChan)
+	if len(results) != 2 {
+		t.Errorf("Expected 2 streamed results, got %d", len(results))
+	}
+
+	statuses := make(map[string]string)
+	for _, event := range events {
+		statuses[event.Source] = event.Status
+	}
+	if statuses["ok"] != models.SearchStatusOK || statuses["stuck"] != models.SearchStatusTimeout {
+		t.Errorf("Unexpected statuses: %v", statuses)
+	}
+}
+
+// drainStream collects everything StreamSearch sends until both channels are closed
+func drainStream(resultChan <-chan models.SearchResult, sourceCompleteChan <-chan models.SourceSearchStatus) ([]models.SearchResult, []models.SourceSearchStatus) {
+	var results []models.SearchResult
+	var events []models.SourceSearchStatus
+
+	for resultChan != nil || sourceCompleteChan != nil {
+		select {
+		case result, ok := <-resultChan:
+			if !ok {
+				resultChan = nil
+				continue
+			}
+			results = append(results, result)
+		case event, ok := <-sourceCompleteChan:
+			if !ok {
+				sourceCompleteChan = nil
+				continue
+			}
+			events = append(events, event)
+		}
+	}
+
+	return results, events
+}
+
+func TestSubtitleService_StreamSearchWithConcurrentRegistration(t *testing.T) {
+	var initial []sources.Source
+	for i := 0; i < 4; i++ {
+		source := newFakeSource(fmt.Sprintf("source-%d", i), i, "a", "b", "c")
+		source.delay = time.Millisecond
+		initial = append(initial, source)
+	}
+	service, manager := newTestService(initial...)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var mutators sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		mutators.Add(1)
+		go func(worker int) {
+			defer mutators.Done()
+			for j := 0; ctx.Err() == nil; j++ {
+				name := fmt.Sprintf("extra-%d", worker)
+				manager.RegisterSource(newFakeSource(name, j%3, "x"))
+				service.GetAvailableSources()
+				manager.UnregisterSource(name)
+				if j%50 == 0 {
+					manager.ReplaceSources(initial)
+				}
+			}
+		}(i)
+	}
+
+	var searches sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		searches.Add(1)
+		go func() {
+			defer searches.Done()
+
+			resultChan := make(chan models.SearchResult, 10)
+			sourceCompleteChan := make(chan models.SourceSearchStatus, 10)
+			service.StreamSearch(ctx, models.SearchRequest{Query: "q"}, resultChan, sourceCompleteChan)
+
+			results, events := drainStream(resultChan, sourceCompleteChan)
+
+			total := 0
+			for _, event := range events {
+				total += event.Count
+			}
+			if total != len(results) {
+				t.Errorf("Completion counts add up to %d, but %d results were streamed", total, len(results))
+			}
+		}()
+	}
+
+	searches.Wait()
+	cancel()
+	mutators.Wait()
+}
+
+func TestSubtitleService_ListEpisodes(t *testing.T) {
+	series := &fakeSeriesSource{newFakeSource("series", 1, "Loki S02E03", "Loki Finale")}
+	service, _ := newTestService(series, newFakeSource("plain", 2))
+
+	episodes, err := service.ListEpisodes(context.Background(), models.DownloadRequest{Source: "series", URL: "https://series/loki"})
+	if err != nil {
+		t.Fatalf("ListEpisodes failed: %v", err)
+	}
+	if len(episodes) != 2 {
+		t.Fatalf("Expected 2 episodes, got %+v", episodes)
+	}
+	if episodes[0].Season != 2 || episodes[0].Episode != 3 || episodes[0].Type != models.TypeTV {
+		t.Errorf("Expected parsed season and episode, got %+v", episodes[0])
+	}
+	if episodes[1].Episode != 2 {
+		t.Errorf("Expected the item position as the episode when the title has none, got %+v", episodes[1])
+	}
+
+	if _, err := service.ListEpisodes(context.Background(), models.DownloadRequest{Source: "plain", URL: "https://plain/x"}); err == nil {
+		t.Error("Expected an error for a source that cannot list episodes")
+	}
+}
+
+func TestSubtitleService_SearchCollectsAllPages(t *testing.T) {
+	service, _ := newTestService(newFakePagedSource("paged", []string{"a", "b"}, []string{"c"}))
+
+	response, err := service.Search(context.Background(), models.SearchRequest{Query: "q"})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+
+	if len(response.Results) != 3 {
+		t.Errorf("Expected results from every page, got %+v", response.Results)
+	}
+	if len(response.Sources) != 1 || response.Sources[0].Count != 3 || response.Sources[0].Status != models.SearchStatusOK {
+		t.Errorf("Expected one ok status counting every page, got %+v", response.Sources)
+	}
+}
+
+func TestSubtitleService_StreamSearchDeliversPagesBeforeDeadline(t *testing.T) {
+	paged := newFakePagedSource("paged", []string{"a"}, []string{"b"})
+	paged.hang = make(chan struct{})
+
+	service, _ := newTestServiceWithOptions(Options{SearchTimeout: 50 * time.Millisecond}, paged)
+
+	resultChan := make(chan models.SearchResult, 10)
+	sourceCompleteChan := make(chan models.SourceSearchStatus, 10)
+	service.StreamSearch(context.Background(), models.SearchRequest{Query: "q"}, resultChan, sourceCompleteChan)
+
+	results, events := drainStream(resultChan, sourceCompleteChan)
+	if len(results) != 2 {
+		t.Errorf("Expected the pages delivered before the deadline, got %+v", results)
+	}
+	if len(events) != 1 || events[0].Status != models.SearchStatusTimeout || events[0].Count != 2 {
+		t.Errorf("Expected a single timeout status counting the delivered pages, got %+v", events)
+	}
+}
+
+// countingSource counts upstream searches and blocks each one until release is closed
+type countingSource struct {
+	*fakeSource
+	calls   atomic.Int32
+	release chan struct{}
+}
+
+func (c *countingSource) Search(ctx context.Context, req models.SearchRequest) ([]models.SearchResult, error) {
+	c.calls.Add(1)
+	<-c.release
+	return c.fakeSource.Search(ctx, req)
+}
+
+func TestSubtitleService_SearchCache(t *testing.T) {
+	source := &countingSource{fakeSource: newFakeSource("src", 0, "Oppenheimer (2023)"), release: make(chan struct{})}
+	service, _ := newTestServiceWithOptions(Options{SearchCache: cache.NewMemory(1<<20, time.Minute)}, source)
+
+	var wg sync.WaitGroup
+	statuses := make([]string, 20)
+	for i := range statuses {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			response, err := service.Search(context.Background(), models.SearchRequest{Query: "oppenheimer"})
+			if err != nil || len(response.Results) != 1 {
+				t.Errorf("Search() = %+v, %v", response, err)
+				return
+			}
+			statuses[i] = response.Sources[0].Cache
+		}(i)
+	}
+
+	for source.calls.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(source.release)
+	wg.Wait()
+
+	if calls := source.calls.Load(); calls != 1 {
+		t.Errorf("Upstream searched %d times, want 1", calls)
+	}
+	misses := 0
+	for _, status := range statuses {
+		if status == models.CacheMiss {
+			misses++
+		}
+	}
+	if misses != 1 {
+		t.Errorf("Expected exactly one miss, got statuses %v", statuses)
+	}
+
+	// Queries are normalized and filters do not affect the cache key
+	response, err := service.Search(context.Background(), models.SearchRequest{Query: "  Oppenheimer ", Year: 2023})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if response.Sources[0].Cache != models.CacheHit || len(response.Results) != 1 || source.calls.Load() != 1 {
+		t.Errorf("Expected a cache hit, got %+v after %d upstream searches", response.Sources[0], source.calls.Load())
+	}
+}
+
+// versionedSource is a source reloaded from a definition with the given version
+type versionedSource struct {
+	*fakeSource
+	version string
+}
+
+func (v *versionedSource) Version() string { return v.version }
+
+func TestSubtitleService_SearchCacheSkipsOldVersions(t *testing.T) {
+	store := cache.NewMemory(1<<20, time.Minute)
+	service, manager := newTestServiceWithOptions(Options{SearchCache: store},
+		&versionedSource{fakeSource: newFakeSource("src", 0, "Broken"), version: "1"})
+
+	search := func() *models.SearchResponse {
+		t.Helper()
+		response, err := service.Search(context.Background(), models.SearchRequest{Query: "dune"})
+		if err != nil {
+			t.Fatalf("Search failed: %v", err)
+		}
+		return response
+	}
+	search()
+
+	manager.ReplaceSources([]sources.Source{&versionedSource{fakeSource: newFakeSource("src", 0, "Dune (2021)"), version: "2"}})
+	response := search()
+	if response.Sources[0].Cache != models.CacheMiss || len(response.Results) != 1 || response.Results[0].Title != "Dune (2021)" {
+		t.Errorf("Expected the reloaded source to be searched again, got %+v", response)
+	}
+}
+
+func TestSubtitleService_SearchCacheSkipsErrors(t *testing.T) {
+	failing := newFakeSource("failing", 0)
+	failing.err = fmt.Errorf("boom")
+	service, _ := newTestServiceWithOptions(Options{SearchCache: cache.NewMemory(1<<20, time.Minute)}, failing)
+
+	for i := 0; i < 2; i++ {
+		response, err := service.Search(context.Background(), models.SearchRequest{Query: "q"})
+		if err != nil {
+			t.Fatalf("Search failed: %v", err)
+		}
+		if status := response.Sources[0]; status.Status != models.SearchStatusError || status.Cache != models.CacheMiss {
+			t.Errorf("Search %d: expected an uncached error, got %+v", i, status)
+		}
+	}
+}
+
+// flakyDownloadSource counts downloads and fails them while down is set
+type flakyDownloadSource struct {
+	*fakeSource
+	downloads int
+	down      bool
+}
+
+func (f *flakyDownloadSource) Download(ctx context.Context, url string) ([]byte, string, error) {
+	f.downloads++
+	if f.down {
+		return nil, "", fmt.Errorf("site is down")
+	}
+	return []byte("archive " + url), "sub.zip", nil
+}
+
+func TestSubtitleService_DownloadCache(t *testing.T) {
+	downloads, err := cache.OpenDisk(t.TempDir(), 1<<20, time.Hour)
+	if err != nil {
+		t.Fatalf("OpenDisk() failed: %v", err)
+	}
+
+	source := &flakyDownloadSource{fakeSource: newFakeSource("src", 0)}
+	service, _ := newTestServiceWithOptions(Options{Downloads: downloads, DownloadTTL: time.Minute}, source)
+	req := models.DownloadRequest{Source: "src", URL: "https://src/post"}
+
+	for i := 0; i < 2; i++ {
+		content, filename, err := service.Download(context.Background(), req)
+		if err != nil || string(content) != "archive https://src/post" || filename != "sub.zip" {
+			t.Fatalf("Download() = %q, %q, %v", content, filename, err)
+		}
+	}
+	if source.downloads != 1 {
+		t.Errorf("Expected the repeat download to be served from the cache, got %d downloads", source.downloads)
+	}
+
+	// Past the TTL the site is asked again, and the stale copy is served when it fails
+	service.options.DownloadTTL = time.Nanosecond
+	source.down = true
+	content, _, err := service.Download(context.Background(), req)
+	if err != nil || string(content) != "archive https://src/post" {
+		t.Errorf("Expected the stale copy while the site is down, got %q, %v", content, err)
+	}
+	if source.downloads != 2 {
+		t.Errorf("Expected the site to be asked again after the TTL, got %d downloads", source.downloads)
+	}
+
+	if _, _, err := service.Download(context.Background(), models.DownloadRequest{Source: "src", URL: "https://src/other"}); err == nil {
+		t.Error("Expected an error for an uncached download while the site is down")
+	}
+}
+
+func TestSubtitleService_SearchCacheSurvivesRestartOnDisk(t *testing.T) {
+	dir := t.TempDir()
+	source := newFakeSource("src", 0, "Oppenheimer (2023)")
+
+	for i, want := range []string{models.CacheMiss, models.CacheHit} {
+		store, err := cache.Open(cache.BackendDisk, dir, 1<<20, time.Minute)
+		if err != nil {
+			t.Fatalf("Open() failed: %v", err)
+		}
+		service, _ := newTestServiceWithOptions(Options{SearchCache: store}, source)
+
+		response, err := service.Search(context.Background(), models.SearchRequest{Query: "oppenheimer"})
+		if err != nil {
+			t.Fatalf("Search failed: %v", err)
+		}
+		if got := response.Sources[0].Cache; got != want || len(response.Results) != 1 {
+			t.Errorf("Search %d: cache = %q with %d results, want %q", i, got, len(response.Results), want)
+		}
+
+		if err := store.Close(); err != nil {
+			t.Fatalf("Close() failed: %v", err)
+		}
+	}
+}
+
+// archiveSource serves the same download for every post
+type archiveSource struct {
+	*fakeSource
+	content  []byte
+	filename string
+}
+
+func (a *archiveSource) Download(ctx context.Context, url string) ([]byte, string, error) {
+	return a.content, a.filename, nil
+}
+
+func zipArchive(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, _ := zw.Create(name)
+		w.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("Close() failed: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestSubtitleService_DownloadFile(t *testing.T) {
+	source := &archiveSource{
+		fakeSource: newFakeSource("src", 0),
+		content:    zipArchive(t, map[string]string{"Dune/Dune.2021.srt": "subtitle"}),
+		filename:   "dune.zip",
+	}
+	service, _ := newTestService(source)
+
+	file, err := service.DownloadFile(context.Background(), models.DownloadRequest{Source: "src", URL: "u", File: "Dune/Dune.2021.srt"})
+	if err != nil || string(file.Content) != "subtitle" || file.Filename != "Dune.2021.srt" {
+		t.Errorf("DownloadFile() = %+v, %v", file, err)
+	}
+
+	if _, err := service.DownloadFile(context.Background(), models.DownloadRequest{Source: "src", URL: "u", File: "other.srt"}); !errors.Is(err, archive.ErrFileNotFound) {
+		t.Errorf("Expected ErrFileNotFound, got %v", err)
+	}
+
+	// A post linking the subtitle itself serves it under its own name
+	source.content, source.filename = []byte("plain"), "Dune.srt"
+	file, err = service.DownloadFile(context.Background(), models.DownloadRequest{Source: "src", URL: "u", File: "Dune.srt"})
+	if err != nil || string(file.Content) != "plain" {
+		t.Errorf("DownloadFile() of a plain subtitle = %+v, %v", file, err)
+	}
+}
+
+func TestSubtitleService_DownloadFileConvertsLegacyFonts(t *testing.T) {
+	legacy := "1\n00:00:01,000 --> 00:00:02,000\n<i>wdhqfndajka' Tn fldfydu o@</i>\n\n2\n00:00:03,000 --> 00:00:04,000\nuu ;uqkag wdorh lrkjd'\n"
+	source := &archiveSource{
+		fakeSource: newFakeSource("src", 0),
+		content:    zipArchive(t, map[string]string{"Dune.srt": legacy}),
+		filename:   "dune.zip",
+	}
+	service, _ := newTestService(source)
+
+	file, err := service.DownloadFile(context.Background(), models.DownloadRequest{Source: "src", URL: "u", File: "Dune.srt", Convert: models.ConvertUnicode})
+	if err != nil {
+		t.Fatalf("DownloadFile failed: %v", err)
+	}
+	content := string(file.Content)
+	if !strings.Contains(content, "00:00:01,000 --> 00:00:02,000\n<i>ආයුබෝවන්") || !strings.Contains(content, "මම තමුන්ට") {
+		t.Errorf("Expected the cue text converted and timings kept, got %q", content)
+	}
+
+	// Converting needs a single file, not the whole archive
+	if _, err := service.DownloadFile(context.Background(), models.DownloadRequest{Source: "src", URL: "u", Convert: models.ConvertUnicode}); !errors.Is(err, archive.ErrUnsupported) {
+		t.Errorf("Expected ErrUnsupported without a file, got %v", err)
+	}
+
+	// Unicode subtitles are left alone
+	source.content, source.filename = []byte("1\n00:00:01,000 --> 00:00:02,000\nආයුබෝවන්\n"), "Dune.srt"
+	file, err = service.DownloadFile(context.Background(), models.DownloadRequest{Source: "src", URL: "u", Convert: models.ConvertUnicode})
+	if err != nil || string(file.Content) != string(source.content) {
+		t.Errorf("DownloadFile() of a Unicode subtitle = %+v, %v", file, err)
+	}
+}
+
+func TestSubtitleService_DownloadFileReencodes(t *testing.T) {
+	source := &archiveSource{
+		fakeSource: newFakeSource("src", 0),
+		content:    []byte("1\r\n00:00:01,000 --> 00:00:02,000\r\nCaf\xe9\r\n"),
+		filename:   "Dune.srt",
+	}
+	service, _ := newTestService(source)
+
+	file, err := service.DownloadFile(context.Background(), models.DownloadRequest{Source: "src", URL: "u", Encoding: charset.UTF8BOM})
+	if err != nil {
+		t.Fatalf("DownloadFile failed: %v", err)
+	}
+	if file.Encoding != charset.Windows1252 || string(file.Content) != "\xEF\xBB\xBF1\r\n00:00:01,000 --> 00:00:02,000\r\nCafé\r\n" {
+		t.Errorf("DownloadFile() = %q in %s, want UTF-8 with a byte order mark from windows-1252", file.Content, file.Encoding)
+	}
+
+	file, err = service.DownloadFile(context.Background(), models.DownloadRequest{Source: "src", URL: "u"})
+	if err != nil || string(file.Content) != string(source.content) {
+		t.Errorf("DownloadFile() without an encoding = %q, %v, want the original bytes", file.Content, err)
+	}
+}
+
+func TestSubtitleService_DownloadFileConvertsFormat(t *testing.T) {
+	source := &archiveSource{
+		fakeSource: newFakeSource("src", 0),
+		content:    zipArchive(t, map[string]string{"Dune.srt": "1\r\n00:00:01,500 --> 00:00:02,000\r\n<i>ආයුබෝවන්</i>\r\n"}),
+		filename:   "dune.zip",
+	}
+	service, _ := newTestService(source)
+
+	file, err := service.DownloadFile(context.Background(), models.DownloadRequest{Source: "src", URL: "u", File: "Dune.srt", Format: subtitle.FormatVTT})
+	if err != nil {
+		t.Fatalf("DownloadFile failed: %v", err)
+	}
+	if file.Filename != "Dune.vtt" || string(file.Content) != "WEBVTT\n\n00:00:01.500 --> 00:00:02.000\n<i>ආයුබෝවන්</i>\n\n" {
+		t.Errorf("DownloadFile() = %q, %q", file.Filename, file.Content)
+	}
+
+	source.content, source.filename = []byte("not a subtitle"), "Dune.srt"
+	if _, err := service.DownloadFile(context.Background(), models.DownloadRequest{Source: "src", URL: "u", Format: subtitle.FormatVTT}); !errors.Is(err, subtitle.ErrUnknownFormat) {
+		t.Errorf("Expected ErrUnknownFormat, got %v", err)
+	}
+}
+
+func TestSubtitleService_DownloadFileRetimes(t *testing.T) {
+	source := &archiveSource{
+		fakeSource: newFakeSource("src", 0),
+		content:    []byte("1\n00:00:10,000 --> 00:00:12,000\nFirst\n\n2\n00:01:40,000 --> 00:01:42,000\nLast\n"),
+		filename:   "Dune.srt",
+	}
+	service, _ := newTestService(source)
+
+	file, err := service.DownloadFile(context.Background(), models.DownloadRequest{Source: "src", URL: "u", Offset: -2 * time.Second})
+	if err != nil {
+		t.Fatalf("DownloadFile failed: %v", err)
+	}
+	if file.Filename != "Dune.srt" || !strings.HasPrefix(string(file.Content), "1\n00:00:08,000 --> 00:00:10,000\nFirst\n") {
+		t.Errorf("Shifted DownloadFile() = %q, %q", file.Filename, file.Content)
+	}
+
+	sync := []models.SyncPoint{{From: 10 * time.Second, To: 11 * time.Second}, {From: 100 * time.Second, To: 191 * time.Second}}
+	file, err = service.DownloadFile(context.Background(), models.DownloadRequest{Source: "src", URL: "u", Sync: sync, Format: subtitle.FormatVTT})
+	if err != nil {
+		t.Fatalf("DownloadFile failed: %v", err)
+	}
+	if !strings.Contains(string(file.Content), "00:00:11.000 --> 00:00:15.000\nFirst") || !strings.Contains(string(file.Content), "00:03:11.000 --> 00:03:15.000\nLast") {
+		t.Errorf("Synced DownloadFile() = %q", file.Content)
+	}
+
+	if _, err := service.DownloadFile(context.Background(), models.DownloadRequest{Source: "src", URL: "u", FromFPS: 25}); !errors.Is(err, subtitle.ErrInvalidTiming) {
+		t.Errorf("Expected ErrInvalidTiming without a target frame rate, got %v", err)
+	}
+}
+
+func TestSubtitleService_Merge(t *testing.T) {
+	source := &archiveSource{
+		fakeSource: newFakeSource("src", 0),
+		content:    zipArchive(t, map[string]string{"Dune.srt": "1\n00:00:01,000 --> 00:00:03,000\nආයුබෝවන්\n"}),
+		filename:   "dune.zip",
+	}
+	service, _ := newTestService(source)
+	english := []byte("WEBVTT\n\n00:01.100 --> 00:03.000\nHello\n")
+
+	file, err := service.Merge(context.Background(), models.MergeRequest{
+		DownloadRequest: models.DownloadRequest{Source: "src", URL: "u", File: "Dune.srt"},
+		Secondary:       english,
+		SecondaryName:   "Dune.en.vtt",
+	})
+	if err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+	if file.Filename != "Dune.merged.srt" || string(file.Content) != "1\n00:00:01,000 --> 00:00:03,000\nආයුබෝවන්\nHello\n\n" {
+		t.Errorf("Merge() = %q, %q", file.Filename, file.Content)
+	}
+
+	file, err = service.Merge(context.Background(), models.MergeRequest{
+		DownloadRequest: models.DownloadRequest{Source: "src", URL: "u", File: "Dune.srt", Format: subtitle.FormatASS},
+		Secondary:       english,
+		SecondaryName:   "Dune.en.vtt",
+	})
+	if err != nil || file.Filename != "Dune.merged.ass" || !strings.Contains(string(file.Content), "Secondary,,0,0,0,,Hello") {
+		t.Errorf("Merge() to ASS = %+v, %v", file, err)
+	}
+
+	_, err = service.Merge(context.Background(), models.MergeRequest{
+		DownloadRequest: models.DownloadRequest{Source: "src", URL: "u", File: "Dune.srt"},
+		Secondary:       []byte("not a subtitle"),
+		SecondaryName:   "notes.txt",
+	})
+	if !errors.Is(err, subtitle.ErrUnknownFormat) {
+		t.Errorf("Expected ErrUnknownFormat for an invalid upload, got %v", err)
+	}
+}
+
+func TestSubtitleService_Validate(t *testing.T) {
+	source := &archiveSource{
+		fakeSource: newFakeSource("src", 0),
+		content: zipArchive(t, map[string]string{
+			"Dune.srt": "1\n00:00:01,000 --> 00:00:03,000\nආයුබෝවන්\n\n3\n00:00:02,000 --> 00:00:04,000\nHi\n",
+		}),
+		filename: "dune.zip",
+	}
+	service, _ := newTestService(source)
+
+	report, err := service.Validate(context.Background(), models.DownloadRequest{Source: "src", URL: "u", File: "Dune.srt"})
+	if err != nil {
+		t.Fatalf("Validate failed: %v", err)
+	}
+	if report.Format != subtitle.FormatSRT || report.Cues != 2 || report.Start != "00:00:01.000" || report.End != "00:00:04.000" {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+	if report.Valid || len(report.Issues) != 2 || report.Issues[0].Kind != subtitle.IssueOverlap || report.Issues[1].Kind != subtitle.IssueNumbering {
+		t.Errorf("Expected an overlap and a numbering issue, got %+v", report.Issues)
+	}
+	if report.SinhalaPercent != 80 {
+		t.Errorf("SinhalaPercent = %v, want 80", report.SinhalaPercent)
+	}
+
+	// Rewriting the file renumbers its cues, the report describes the file as served
+	file, err := service.DownloadFile(context.Background(), models.DownloadRequest{Source: "src", URL: "u", File: "Dune.srt", Format: subtitle.FormatSRT, Offset: time.Second, Report: true})
+	if err != nil || file.Report == nil || file.Report.Start != "00:00:02.000" || len(file.Report.Issues) != 1 {
+		t.Errorf("DownloadFile() report = %+v, %v", file.Report, err)
+	}
+
+	source.content, source.filename = []byte("1\r\n00:00:01,000 --> 00:00:02,000\r\nCaf\xe9\r\n"), "Cafe.srt"
+	report, err = service.Validate(context.Background(), models.DownloadRequest{Source: "src", URL: "u"})
+	if err != nil || report.Encoding != charset.Windows1252 || len(report.Issues) != 1 || report.Issues[0].Kind != subtitle.IssueEncoding {
+		t.Errorf("Expected an encoding issue, got %+v, %v", report, err)
+	}
+
+	// A file the report cannot describe is still served, only Validate fails on it
+	source.content, source.filename = []byte("not a subtitle"), "Notes.sub"
+	file, err = service.DownloadFile(context.Background(), models.DownloadRequest{Source: "src", URL: "u", Report: true})
+	if err != nil || file.Report != nil || string(file.Content) != "not a subtitle" {
+		t.Errorf("Expected the file without a report, got %+v, %v", file, err)
+	}
+	if _, err := service.Validate(context.Background(), models.DownloadRequest{Source: "src", URL: "u"}); !errors.Is(err, subtitle.ErrUnknownFormat) {
+		t.Errorf("Expected ErrUnknownFormat from Validate, got %v", err)
+	}
+}
+
+type watermarkSource struct {
+	*archiveSource
+	patterns []string
+}
+
+func (w *watermarkSource) WatermarkPatterns() []string {
+	return w.patterns
+}
+
+func TestSubtitleService_DownloadFileRepairs(t *testing.T) {
+	source := &watermarkSource{
+		archiveSource: &archiveSource{
+			fakeSource: newFakeSource("src", 0),
+			content: []byte("1\r\n00:00:00,500 --> 00:00:02,000\r\nTranslated by Kasun - visit example.lk\r\n\r\n" +
+				"2\r\n00:00:02,000 --> 00:00:02,900\r\nDune\r\nexample.lk\r\n\r\n" +
+				"5\r\n00:00:03,000 --> 00:00:05,000\r\nFirst\r\n\r\n6\r\n00:00:04,000 --> 00:00:06,000\r\nSecond\r\n\r\n" +
+				"7\r\n00:00:07,000 --> 00:00:08,000\r\n \r\n"),
+			filename: "Dune.srt",
+		},
+		patterns: []string{"EXAMPLE.LK"},
+	}
+	service, _ := newTestService(source)
+
+	file, err := service.DownloadFile(context.Background(), models.DownloadRequest{Source: "src", URL: "u", Repair: true, Report: true})
+	if err != nil {
+		t.Fatalf("DownloadFile failed: %v", err)
+	}
+
+	want := "1\n00:00:02,000 --> 00:00:02,900\nDune\n\n2\n00:00:03,000 --> 00:00:04,000\nFirst\n\n3\n00:00:04,000 --> 00:00:06,000\nSecond\n\n"
+	if file.Filename != "Dune.srt" || string(file.Content) != want {
+		t.Errorf("DownloadFile() = %q, %q, want %q", file.Filename, file.Content, want)
+	}
+	if !file.Report.Valid {
+		t.Errorf("Expected the repaired file to be valid, got %+v", file.Report.Issues)
+	}
+}
+
+func TestSubtitleService_DownloadContents(t *testing.T) {
+	source := &archiveSource{
+		fakeSource: newFakeSource("src", 0),
+		content: zipArchive(t, map[string]string{
+			"Dune.srt":   "1\n00:00:01,000 --> 00:00:02,000\nආයුබෝවන්\n\n2\n00:00:03,000 --> 00:00:04,000\nHi\n",
+			"Dune.ass":   "[Script Info]\n[V4+ Styles]\n[Events]\nDialogue: 0,0:00:01.00,0:00:02.00,Default,,0,0,0,,Hi\n",
+			"readme.txt": "visit our site",
+		}),
+		filename: "dune.zip",
+	}
+	service, _ := newTestService(source)
+
+	response, err := service.DownloadContents(context.Background(), models.DownloadRequest{Source: "src", URL: "u"})
+	if err != nil {
+		t.Fatalf("DownloadContents failed: %v", err)
+	}
+
+	if response.Archive != archive.FormatZIP || response.Filename != "dune.zip" || len(response.Files) != 2 {
+		t.Fatalf("Unexpected response: %+v", response)
+	}
+	srt := response.Files[1]
+	if srt.Name != "Dune.srt" || srt.Format != "srt" || srt.Encoding != "utf-8" || srt.Cues != 2 {
+		t.Errorf("Unexpected srt description: %+v", srt)
+	}
+	if ass := response.Files[0]; ass.Format != "ass" || ass.Cues != 1 {
+		t.Errorf("Unexpected ass description: %+v", ass)
+	}
+}
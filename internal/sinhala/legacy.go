@@ -0,0 +1,140 @@
+// Package sinhala converts Sinhala text typed in legacy ASCII-mapped fonts, such as
+// FM Abhaya and Kaputa, to Unicode.
+package sinhala
+
+import (
+	"strings"
+	"unicode"
+)
+
+const (
+	rakaransaya = "්‍ර"
+	yansaya     = "්‍ය"
+)
+
+// consonants maps the keys of the Wijesekara layout used by the legacy fonts to consonants
+var consonants = map[string]string{
+	"l": "ක", "L": "ඛ", ".": "ග", ">": "ඝ",
+	"p": "ච", "P": "ඡ", "c": "ජ", "C": "ඣ", "[": "ඤ", "{": "ඥ",
+	"g": "ට", "G": "ඨ", "v": "ඩ", "V": "ඪ", "K": "ණ",
+	";": "ත", ":": "ථ", "o": "ද", "O": "ධ", "k": "න",
+	"m": "ප", "M": "ඵ", "n": "බ", "N": "භ", "u": "ම", "U": "ඹ",
+	"h": "ය", "r": "ර", ",": "ල", "j": "ව",
+	"Y": "ශ", "I": "ෂ", "i": "ස", "y": "හ", "<": "ළ", "F": "ෆ",
+	// Glyphs the FM fonts add on top of the layout
+	"|": "ඳ", "`": "ඬ", "z": "ඟ",
+}
+
+// others maps the remaining keys: independent vowels, vowel signs and marks.
+// Longer key sequences come first so they win over their prefixes.
+var others = [][2]string{
+	{"wd", "ආ"}, {"we", "ඇ"}, {"wE", "ඈ"}, {"w", "අ"},
+	{"b", "ඉ"}, {"B", "ඊ"}, {"W", "උ"}, {"R", "ඍ"},
+	{"ta", "ඒ"}, {"t", "එ"}, {"ft", "ඓ"},
+	{"Ta", "ඕ"}, {"T", "ඔ"},
+	{"J", "ළු"},
+	{"%", rakaransaya}, {"H", yansaya},
+	{"a", "්"}, {"d", "ා"}, {"e", "ැ"}, {"E", "ෑ"},
+	{"s", "ි"}, {"S", "ී"}, {"q", "ු"}, {"Q", "ූ"},
+	{"DD", "ෲ"}, {"D", "ෘ"}, {"A", "ෟ"},
+	{"x", "ං"}, {"X", "ඃ"},
+	// The FM fonts use the punctuation keys for letters, so punctuation moves elsewhere
+	{"'", "."}, {"@", "?"}, {"\"", ","}, {"=", "ු"}, {"+", "ූ"},
+}
+
+// converter is built once from the tables. The legacy fonts draw the kombuva (the "f"
+// key) before the consonant it belongs to, Unicode stores it after, so every kombuva
+// combination is listed ahead of the single keys.
+var converter = newConverter()
+
+func newConverter() *strings.Replacer {
+	var pairs []string
+
+	// Medial signs typed between the consonant and its vowel sign
+	medials := []string{"%", "H", ""}
+	kombuvaSigns := [][2]string{
+		{"da", "ෝ"}, // ෝ
+		{"d", "ො"},  // ො
+		{"A", "ෞ"},  // ෞ
+		{"a", "ේ"},  // ේ
+		{"", "ෙ"},   // ෙ
+	}
+	medialText := map[string]string{"%": rakaransaya, "H": yansaya, "": ""}
+
+	for key, consonant := range consonants {
+		for _, medial := range medials {
+			pairs = append(pairs, "ff"+key+medial, consonant+medialText[medial]+"ෛ") // ෛ
+			for _, sign := range kombuvaSigns {
+				pairs = append(pairs, "f"+key+medial+sign[0], consonant+medialText[medial]+sign[1])
+			}
+		}
+	}
+
+	// strings.Replacer tries the old strings in argument order at each position,
+	// so longer combinations must come before shorter ones
+	sortByLength(pairs)
+
+	for _, other := range others {
+		pairs = append(pairs, other[0], other[1])
+	}
+	for key, consonant := range consonants {
+		pairs = append(pairs, key, consonant)
+	}
+
+	return strings.NewReplacer(pairs...)
+}
+
+func sortByLength(pairs []string) {
+	for i := 2; i < len(pairs); i += 2 {
+		for j := i; j >= 2 && len(pairs[j]) > len(pairs[j-2]); j -= 2 {
+			pairs[j], pairs[j-2] = pairs[j-2], pairs[j]
+			pairs[j+1], pairs[j-1] = pairs[j-1], pairs[j+1]
+		}
+	}
+}
+
+// ToUnicode converts text typed in a legacy Sinhala font to Unicode. The keys of the
+// Wijesekara layout and the extra glyphs of the FM fonts are converted.
+func ToUnicode(text string) string {
+	return converter.Replace(text)
+}
+
+// IsLegacy reports whether text looks like Sinhala typed in a legacy font rather than
+// English. It counts key combinations that are common in legacy Sinhala and rare in
+// English, such as a "q" (the u vowel sign) not followed by "u", a kombuva "f" in front
+// of a consonant, a "j" (va) without a vowel after it, and ";" or "%" inside a word.
+func IsLegacy(text string) bool {
+	runes := []rune(text)
+	at := func(i int) rune {
+		if i < 0 || i >= len(runes) {
+			return 0
+		}
+		return runes[i]
+	}
+
+	words, signals := 0, 0
+	for i, r := range runes {
+		if isASCIILetter(r) && !isASCIILetter(at(i-1)) {
+			words++
+		}
+
+		next := at(i + 1)
+		_, nextIsConsonant := consonants[string(next)]
+		switch {
+		case r == 'q' && next != 'u':
+			signals++
+		case r == 'f' && nextIsConsonant && (!strings.ContainsRune("lriouy", next) || at(i+2) == 'd'):
+			signals++
+		case r == 'j' && isASCIILetter(next) && !strings.ContainsRune("aeiou", next):
+			signals++
+		case (r == ';' || r == '%') && isASCIILetter(at(i-1)) && isASCIILetter(next):
+			signals++
+		}
+	}
+
+	return signals >= 3 && float64(signals) >= 0.1*float64(words)
+}
+
+func isASCIILetter(r rune) bool {
+	return r < unicode.MaxASCII && unicode.IsLetter(r)
+}
@@ -0,0 +1,49 @@
+package sinhala
+
+import "testing"
+
+func TestToUnicode(t *testing.T) {
+	tests := []struct {
+		legacy string
+		want   string
+	}{
+		{"wdhqfndajka", "ආයුබෝවන්"},
+		{"ud;D", "මාතෘ"},
+		{"fldfydu o", "කොහොම ද"},
+		{"Y%S ,xldj", "ශ්‍රී ලංකාව"},
+		{"fmd;", "පොත"},
+		{"fia", "සේ"},
+		{"ffi,h", "සෛලය"},
+		{"m%YakH", "ප්‍රශ්න්‍ය"},
+		{"f.%", "ග්‍රෙ"},
+		{"wdhqfndajka' Tn fldfydu o@ uu fyd|ska bkakjd'", "ආයුබෝවන්. ඔබ කොහොම ද? මම හොඳින් ඉන්නවා."},
+		{"uu ;uqkag wdorh lrkjd' Tn fldfydu o@", "මම තමුන්ට ආදරය කරනවා. ඔබ කොහොම ද?"},
+		{".z\" mq`q", "ගඟ, පුඬු"},
+	}
+
+	for _, tt := range tests {
+		if got := ToUnicode(tt.legacy); got != tt.want {
+			t.Errorf("ToUnicode(%q) = %q, want %q", tt.legacy, got, tt.want)
+		}
+	}
+}
+
+func TestIsLegacy(t *testing.T) {
+	tests := []struct {
+		text string
+		want bool
+	}{
+		{"wdhqfndajka' Tn fldfydu o@ uu fyd|ska bkakjd'", true},
+		{"uu ;uqkag wdorh lrkjd' Tn fldfydu o@", true},
+		{"Iraq is far. We left on a fjord.", false},
+		{"Hello, how are you? I am fine, thank you. Quite a quiet queue.", false},
+		{"ආයුබෝවන්", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		if got := IsLegacy(tt.text); got != tt.want {
+			t.Errorf("IsLegacy(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
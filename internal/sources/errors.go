@@ -0,0 +1,12 @@
+package sources
+
+import "fmt"
+
+// StatusError is returned when a site answers with an unexpected HTTP status
+type StatusError struct {
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("received non-200 response: %d", e.StatusCode)
+}
@@ -0,0 +1,123 @@
+package sources
+
+import (
+	"context"
+	"ipmanlk/bettercopelk/internal/models"
+	"sync"
+	"time"
+)
+
+const (
+	StatusUnknown = "unknown"
+	StatusUp      = "up"
+	StatusDown    = "down"
+)
+
+// HealthMonitor probes registered sources in the background and keeps
+// their last known status, so availability lookups never block on the network
+type HealthMonitor struct {
+	manager  *Manager
+	interval time.Duration
+
+	mu       sync.RWMutex
+	statuses map[string]models.SourceStatus
+}
+
+func NewHealthMonitor(manager *Manager, interval time.Duration) *HealthMonitor {
+	return &HealthMonitor{
+		manager:  manager,
+		interval: interval,
+		statuses: make(map[string]models.SourceStatus),
+	}
+}
+
+// Start probes all sources immediately and then on every interval until ctx is cancelled
+func (h *HealthMonitor) Start(ctx context.Context) {
+	h.CheckAll()
+
+	ticker := time.NewTicker(h.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			h.CheckAll()
+		}
+	}
+}
+
+// CheckAll probes every registered source concurrently and waits for all probes to finish
+func (h *HealthMonitor) CheckAll() {
+	var wg sync.WaitGroup
+
+	for _, source := range h.manager.GetAllSources() {
+		wg.Add(1)
+		go func(src Source) {
+			defer wg.Done()
+			h.check(src)
+		}(source)
+	}
+
+	wg.Wait()
+}
+
+func (h *HealthMonitor) check(source Source) {
+	start := time.Now()
+	available := source.IsAvailable()
+	latency := time.Since(start)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	status := h.statuses[source.Name()]
+	status.Name = source.Name()
+	status.Available = available
+	status.LatencyMs = latency.Milliseconds()
+	status.LastChecked = &start
+
+	if available {
+		status.Status = StatusUp
+		status.LastSuccess = &start
+		status.ConsecutiveFailures = 0
+	} else {
+		status.Status = StatusDown
+		status.ConsecutiveFailures++
+	}
+
+	h.statuses[source.Name()] = status
+}
+
+// Snapshot returns the last known status of every registered source in registry order.
+// Sources that have not been probed yet are reported as unknown and assumed available.
+func (h *HealthMonitor) Snapshot() []models.SourceStatus {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	var snapshot []models.SourceStatus
+	for _, name := range h.manager.SourceNames() {
+		status, exists := h.statuses[name]
+		if !exists {
+			status = models.SourceStatus{
+				Name:      name,
+				Status:    StatusUnknown,
+				Available: true,
+			}
+		}
+		snapshot = append(snapshot, status)
+	}
+
+	return snapshot
+}
+
+// AvailableSources returns the names of sources that are up or not yet probed
+func (h *HealthMonitor) AvailableSources() []string {
+	var available []string
+	for _, status := range h.Snapshot() {
+		if status.Available {
+			available = append(available, status.Name)
+		}
+	}
+	return available
+}
@@ -0,0 +1,65 @@
+package sources
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHealthMonitor_UnprobedSourcesAreUnknown(t *testing.T) {
+	manager := NewManager()
+	manager.RegisterSource(&fakeSource{name: "b"})
+	manager.RegisterSource(&fakeSource{name: "a"})
+
+	monitor := NewHealthMonitor(manager, time.Minute)
+
+	snapshot := monitor.Snapshot()
+	if len(snapshot) != 2 {
+		t.Fatalf("Expected 2 statuses, got %d", len(snapshot))
+	}
+	if snapshot[0].Name != "a" || snapshot[1].Name != "b" {
+		t.Errorf("Expected statuses sorted by name, got %s, %s", snapshot[0].Name, snapshot[1].Name)
+	}
+	for _, status := range snapshot {
+		if status.Status != StatusUnknown || !status.Available {
+			t.Errorf("Expected %s to be unknown and available, got %s/%v", status.Name, status.Status, status.Available)
+		}
+	}
+}
+
+func TestHealthMonitor_CheckAll(t *testing.T) {
+	down := &fakeSource{name: "down"}
+	manager := NewManager()
+	manager.RegisterSource(&fakeSource{name: "up", available: true})
+	manager.RegisterSource(down)
+
+	monitor := NewHealthMonitor(manager, time.Minute)
+	monitor.CheckAll()
+	monitor.CheckAll()
+
+	available := monitor.AvailableSources()
+	if len(available) != 1 || available[0] != "up" {
+		t.Errorf("AvailableSources() = %v, want [up]", available)
+	}
+
+	for _, status := range monitor.Snapshot() {
+		switch status.Name {
+		case "up":
+			if status.Status != StatusUp || status.LastSuccess == nil || status.ConsecutiveFailures != 0 {
+				t.Errorf("Unexpected status for up source: %+v", status)
+			}
+		case "down":
+			if status.Status != StatusDown || status.LastSuccess != nil || status.ConsecutiveFailures != 2 {
+				t.Errorf("Unexpected status for down source: %+v", status)
+			}
+		}
+	}
+
+	down.available = true
+	monitor.CheckAll()
+
+	for _, status := range monitor.Snapshot() {
+		if status.Name == "down" && (status.ConsecutiveFailures != 0 || status.LastSuccess == nil) {
+			t.Errorf("Expected recovered source to reset failures, got %+v", status)
+		}
+	}
+}
@@ -3,6 +3,8 @@ package sources
 import (
 	"context"
 	"ipmanlk/bettercopelk/internal/models"
+	"sort"
+	"sync"
 )
 
 type Source interface {
@@ -12,7 +14,49 @@ type Source interface {
 	IsAvailable() bool
 }
 
+// Prioritizer is implemented by sources with a configured priority.
+// Lower values are listed first, sources without a priority default to 0.
+type Prioritizer interface {
+	Priority() int
+}
+
+// ItemLister is implemented by sources that can split a post with several downloads,
+// such as a TV series post, into individually downloadable items. The URL of each
+// item can be passed to Download.
+type ItemLister interface {
+	ListItems(ctx context.Context, postURL string) ([]models.SearchResult, error)
+}
+
+// PageSearcher is implemented by sources that fetch several pages of search results.
+// SearchPages calls onPage with the results of each page as soon as it arrives, never
+// concurrently, and returns once every page has been delivered.
+type PageSearcher interface {
+	SearchPages(ctx context.Context, req models.SearchRequest, onPage func([]models.SearchResult)) error
+}
+
+// Weighter is implemented by sources with a configured reliability weight in (0, 1].
+// It scales the relevance score of the source's results, sources without one count as 1.
+type Weighter interface {
+	Weight() float64
+}
+
+// Watermarker is implemented by sources that insert watermarks, such as credits with the
+// site address, into their subtitles. Lines containing any of the patterns are watermarks.
+type Watermarker interface {
+	WatermarkPatterns() []string
+}
+
+// Versioner is implemented by sources built from configuration that can be reloaded. The
+// version changes whenever the configuration does, so results cached by an earlier
+// version are not served.
+type Versioner interface {
+	Version() string
+}
+
+// Manager is the registry of sources. It is safe for concurrent use, so sources
+// can be registered, removed or replaced while searches are running.
 type Manager struct {
+	mu      sync.RWMutex
 	sources map[string]Source
 }
 
@@ -22,25 +66,80 @@ func NewManager() *Manager {
 	}
 }
 
+// RegisterSource adds a source, replacing any existing source with the same name
 func (m *Manager) RegisterSource(source Source) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	m.sources[source.Name()] = source
 }
 
+// UnregisterSource removes a source and reports whether it was registered
+func (m *Manager) UnregisterSource(name string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, exists := m.sources[name]
+	delete(m.sources, name)
+	return exists
+}
+
+// ReplaceSources atomically swaps the whole set of registered sources.
+// Searches that already hold a source keep using it until they finish.
+func (m *Manager) ReplaceSources(sources []Source) {
+	replacement := make(map[string]Source, len(sources))
+	for _, source := range sources {
+		replacement[source.Name()] = source
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sources = replacement
+}
+
 func (m *Manager) GetSource(name string) (Source, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
 	source, exists := m.sources[name]
 	return source, exists
 }
 
-func (m *Manager) GetAllSources() map[string]Source {
-	return m.sources
-}
+// GetAllSources returns a snapshot of the registered sources ordered by priority and then name.
+// The returned slice is owned by the caller.
+func (m *Manager) GetAllSources() []Source {
+	m.mu.RLock()
+	sources := make([]Source, 0, len(m.sources))
+	for _, source := range m.sources {
+		sources = append(sources, source)
+	}
+	m.mu.RUnlock()
 
-func (m *Manager) GetAvailableSources() []string {
-	var available []string
-	for name, source := range m.sources {
-		if source.IsAvailable() {
-			available = append(available, name)
+	sort.Slice(sources, func(i, j int) bool {
+		pi, pj := priorityOf(sources[i]), priorityOf(sources[j])
+		if pi != pj {
+			return pi < pj
 		}
+		return sources[i].Name() < sources[j].Name()
+	})
+
+	return sources
+}
+
+// SourceNames returns the registered source names in the same order as GetAllSources
+func (m *Manager) SourceNames() []string {
+	sources := m.GetAllSources()
+	names := make([]string, len(sources))
+	for i, source := range sources {
+		names[i] = source.Name()
+	}
+	return names
+}
+
+func priorityOf(source Source) int {
+	if p, ok := source.(Prioritizer); ok {
+		return p.Priority()
 	}
-	return available
+	return 0
 }
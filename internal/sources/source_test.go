@@ -0,0 +1,108 @@
+package sources
+
+import (
+	"context"
+	"fmt"
+	"ipmanlk/bettercopelk/internal/models"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+type fakeSource struct {
+	name      string
+	available bool
+	priority  int
+}
+
+func (f *fakeSource) Name() string { return f.name }
+
+func (f *fakeSource) Search(ctx context.Context, req models.SearchRequest) ([]models.SearchResult, error) {
+	return nil, nil
+}
+
+func (f *fakeSource) Download(ctx context.Context, url string) ([]byte, string, error) {
+	return nil, "", nil
+}
+
+func (f *fakeSource) IsAvailable() bool { return f.available }
+
+func (f *fakeSource) Priority() int { return f.priority }
+
+func TestManager_OrderedByPriorityThenName(t *testing.T) {
+	manager := NewManager()
+	manager.RegisterSource(&fakeSource{name: "zoomlk", priority: 1})
+	manager.RegisterSource(&fakeSource{name: "cineru", priority: 2})
+	manager.RegisterSource(&fakeSource{name: "piratelk", priority: 1})
+	manager.RegisterSource(&fakeSource{name: "baiscopelk", priority: 2})
+
+	expected := []string{"piratelk", "zoomlk", "baiscopelk", "cineru"}
+
+	for i := 0; i < 10; i++ {
+		if got := manager.SourceNames(); !reflect.DeepEqual(got, expected) {
+			t.Fatalf("SourceNames() = %v, want %v", got, expected)
+		}
+	}
+}
+
+func TestManager_GetAllSourcesReturnsCopy(t *testing.T) {
+	manager := NewManager()
+	manager.RegisterSource(&fakeSource{name: "a"})
+	manager.RegisterSource(&fakeSource{name: "b"})
+
+	all := manager.GetAllSources()
+	all[0] = &fakeSource{name: "mutated"}
+
+	if got := manager.SourceNames(); !reflect.DeepEqual(got, []string{"a", "b"}) {
+		t.Errorf("Mutating the returned slice changed the registry: %v", got)
+	}
+}
+
+func TestManager_RegisterUnregisterReplace(t *testing.T) {
+	manager := NewManager()
+	manager.RegisterSource(&fakeSource{name: "a"})
+	manager.RegisterSource(&fakeSource{name: "b"})
+
+	replacement := &fakeSource{name: "a", available: true}
+	manager.RegisterSource(replacement)
+	if source, _ := manager.GetSource("a"); source != replacement {
+		t.Error("RegisterSource() did not replace the existing source")
+	}
+
+	if !manager.UnregisterSource("b") {
+		t.Error("UnregisterSource() = false for a registered source")
+	}
+	if manager.UnregisterSource("b") {
+		t.Error("UnregisterSource() = true for an unknown source")
+	}
+
+	manager.ReplaceSources([]Source{&fakeSource{name: "c"}, &fakeSource{name: "d"}})
+	if got := manager.SourceNames(); !reflect.DeepEqual(got, []string{"c", "d"}) {
+		t.Errorf("SourceNames() after ReplaceSources() = %v, want [c d]", got)
+	}
+}
+
+func TestManager_ConcurrentMutation(t *testing.T) {
+	manager := NewManager()
+	var wg sync.WaitGroup
+
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				name := fmt.Sprintf("source-%d-%d", worker, j%5)
+				manager.RegisterSource(&fakeSource{name: name, priority: j % 3})
+				manager.GetSource(name)
+				manager.GetAllSources()
+				manager.SourceNames()
+				if j%10 == 0 {
+					manager.ReplaceSources([]Source{&fakeSource{name: name}})
+				}
+				manager.UnregisterSource(name)
+			}
+		}(i)
+	}
+
+	wg.Wait()
+}
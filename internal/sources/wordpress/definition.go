@@ -0,0 +1,191 @@
+package wordpress
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+//go:embed definitions.json
+var defaultDefinitions []byte
+
+// Definition describes how to search and download from a WordPress-style subtitle site
+type Definition struct {
+	Name    string `json:"name"`
+	BaseURL string `json:"base_url"`
+
+	// Priority orders sources in listings and search results, lower values come first
+	Priority int `json:"priority,omitempty"`
+
+	// Weight is the reliability of the site in (0, 1], used when ranking results
+	Weight float64 `json:"weight,omitempty"`
+
+	// SearchPath is appended to BaseURL, {query} is replaced with the escaped search query
+	SearchPath string `json:"search_path,omitempty"`
+
+	// NextPageSelector matches the link to the next page of search results. PagePath is
+	// the path of any results page, with {page} replaced by the page number; together with
+	// PaginationSelector, which matches the numbered page links, it lets later pages be
+	// fetched concurrently instead of one after another.
+	NextPageSelector   string `json:"next_page_selector,omitempty"`
+	PagePath           string `json:"page_path,omitempty"`
+	PaginationSelector string `json:"pagination_selector,omitempty"`
+
+	// MaxPages limits how many results pages a search fetches (default 3), MaxResults
+	// limits how many results it returns (default unlimited)
+	MaxPages   int `json:"max_pages,omitempty"`
+	MaxResults int `json:"max_results,omitempty"`
+
+	// ResultSelector matches one element per search result. LinkSelector and TitleSelector
+	// are looked up inside it; when empty the result element itself is used.
+	ResultSelector string `json:"result_selector"`
+	LinkSelector   string `json:"link_selector,omitempty"`
+	TitleSelector  string `json:"title_selector,omitempty"`
+
+	// DownloadSelector and DownloadAttribute locate the archive link on a post page
+	DownloadSelector  string `json:"download_selector"`
+	DownloadAttribute string `json:"download_attribute,omitempty"`
+	DownloadMethod    string `json:"download_method,omitempty"`
+
+	// FilenameHeader is checked before Content-Disposition when naming downloads
+	FilenameHeader string `json:"filename_header,omitempty"`
+
+	// IgnorePatterns drop results whose title or URL contains any of the patterns
+	IgnorePatterns []string `json:"ignore_patterns,omitempty"`
+
+	// SeriesPatterns flag results whose title or URL contains any of the patterns as TV series
+	SeriesPatterns []string `json:"series_patterns,omitempty"`
+
+	// CollectionPatterns flag results whose title or URL contains any of the patterns as
+	// collections, their posts are expanded into the films they contain
+	CollectionPatterns []string `json:"collection_patterns,omitempty"`
+
+	// CollectionItemSelector matches one element per film on a collection post, the link
+	// to the film's post is the element itself or the first link inside it.
+	// CollectionTitleSelector is looked up inside it for the film title; when empty the
+	// link text is used.
+	CollectionItemSelector  string `json:"collection_item_selector,omitempty"`
+	CollectionTitleSelector string `json:"collection_title_selector,omitempty"`
+
+	// WatermarkPatterns mark subtitle lines containing any of the patterns, ignoring case, as
+	// the site's watermarks, which repaired downloads leave out
+	WatermarkPatterns []string `json:"watermark_patterns,omitempty"`
+
+	// ItemSelector and ItemAttribute locate the individual downloads on a post that has
+	// several, such as the episodes of a series. They default to the download selector
+	// and attribute, so every download link on the page becomes an item.
+	ItemSelector  string `json:"item_selector,omitempty"`
+	ItemAttribute string `json:"item_attribute,omitempty"`
+
+	// Headers are sent with every request, DownloadHeaders only with the archive request
+	Headers         map[string]string `json:"headers,omitempty"`
+	DownloadHeaders map[string]string `json:"download_headers,omitempty"`
+}
+
+type definitionsFile struct {
+	Sources []Definition `json:"sources"`
+}
+
+// Validate checks required fields and fills in defaults
+func (d *Definition) Validate() error {
+	if d.Name == "" {
+		return fmt.Errorf("source name is required")
+	}
+	if d.BaseURL == "" {
+		return fmt.Errorf("source %s: base_url is required", d.Name)
+	}
+	if d.ResultSelector == "" {
+		return fmt.Errorf("source %s: result_selector is required", d.Name)
+	}
+	if d.DownloadSelector == "" {
+		return fmt.Errorf("source %s: download_selector is required", d.Name)
+	}
+
+	if d.Weight < 0 || d.Weight > 1 {
+		return fmt.Errorf("source %s: weight must be between 0 and 1", d.Name)
+	}
+
+	if d.PagePath != "" && !strings.Contains(d.PagePath, "{page}") {
+		return fmt.Errorf("source %s: page_path must contain {page}", d.Name)
+	}
+	if d.MaxPages < 0 || d.MaxResults < 0 {
+		return fmt.Errorf("source %s: max_pages and max_results must not be negative", d.Name)
+	}
+	if len(d.CollectionPatterns) > 0 && d.CollectionItemSelector == "" {
+		return fmt.Errorf("source %s: collection_patterns require a collection_item_selector", d.Name)
+	}
+
+	d.BaseURL = strings.TrimRight(d.BaseURL, "/")
+
+	if d.Weight == 0 {
+		d.Weight = 1
+	}
+	if d.SearchPath == "" {
+		d.SearchPath = "/?s={query}"
+	}
+	if d.MaxPages == 0 {
+		d.MaxPages = 3
+	}
+	if d.DownloadAttribute == "" {
+		d.DownloadAttribute = "href"
+	}
+	if d.ItemSelector == "" {
+		d.ItemSelector = d.DownloadSelector
+		if d.ItemAttribute == "" {
+			d.ItemAttribute = d.DownloadAttribute
+		}
+	}
+	if d.ItemAttribute == "" {
+		d.ItemAttribute = "href"
+	}
+	if d.DownloadMethod == "" {
+		d.DownloadMethod = "GET"
+	}
+	d.DownloadMethod = strings.ToUpper(d.DownloadMethod)
+
+	return nil
+}
+
+// LoadDefinitions decodes and validates a JSON definitions document
+func LoadDefinitions(r io.Reader) ([]Definition, error) {
+	var file definitionsFile
+
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&file); err != nil {
+		return nil, fmt.Errorf("failed to decode source definitions: %w", err)
+	}
+
+	seen := make(map[string]bool)
+	for i := range file.Sources {
+		if err := file.Sources[i].Validate(); err != nil {
+			return nil, err
+		}
+		if seen[file.Sources[i].Name] {
+			return nil, fmt.Errorf("duplicate source name: %s", file.Sources[i].Name)
+		}
+		seen[file.Sources[i].Name] = true
+	}
+
+	return file.Sources, nil
+}
+
+// LoadDefinitionsFile reads definitions from path
+func LoadDefinitionsFile(path string) ([]Definition, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open source definitions: %w", err)
+	}
+	defer f.Close()
+
+	return LoadDefinitions(f)
+}
+
+// DefaultDefinitions returns the definitions bundled with the binary
+func DefaultDefinitions() ([]Definition, error) {
+	return LoadDefinitions(bytes.NewReader(defaultDefinitions))
+}
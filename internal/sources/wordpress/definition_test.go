@@ -0,0 +1,86 @@
+package wordpress
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDefaultDefinitions(t *testing.T) {
+	definitions, err := DefaultDefinitions()
+	if err != nil {
+		t.Fatalf("DefaultDefinitions() failed: %v", err)
+	}
+
+	expected := []string{"baiscopelk", "cineru", "piratelk", "zoomlk"}
+	if len(definitions) != len(expected) {
+		t.Fatalf("Expected %d definitions, got %d", len(expected), len(definitions))
+	}
+
+	for i, def := range definitions {
+		if def.Name != expected[i] {
+			t.Errorf("Definition %d name = %v, want %v", i, def.Name, expected[i])
+		}
+		if def.DownloadMethod == "" || def.DownloadAttribute == "" || def.SearchPath == "" {
+			t.Errorf("Definition %s is missing defaults: %+v", def.Name, def)
+		}
+	}
+}
+
+func TestLoadDefinitions_Defaults(t *testing.T) {
+	input := `{"sources": [{
+		"name": "example",
+		"base_url": "https://example.com/",
+		"result_selector": ".post a",
+		"download_selector": ".download",
+		"download_method": "post"
+	}]}`
+
+	definitions, err := LoadDefinitions(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("LoadDefinitions() failed: %v", err)
+	}
+
+	def := definitions[0]
+	if def.BaseURL != "https://example.com" {
+		t.Errorf("BaseURL = %v, want trailing slash trimmed", def.BaseURL)
+	}
+	if def.SearchPath != "/?s={query}" {
+		t.Errorf("SearchPath = %v, want default", def.SearchPath)
+	}
+	if def.DownloadAttribute != "href" {
+		t.Errorf("DownloadAttribute = %v, want href", def.DownloadAttribute)
+	}
+	if def.DownloadMethod != "POST" {
+		t.Errorf("DownloadMethod = %v, want POST", def.DownloadMethod)
+	}
+	if def.ItemSelector != ".download" || def.ItemAttribute != "href" {
+		t.Errorf("ItemSelector, ItemAttribute = %v, %v, want the download selector", def.ItemSelector, def.ItemAttribute)
+	}
+}
+
+func TestLoadDefinitions_Invalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"missing name", `{"sources": [{"base_url": "https://a", "result_selector": "a", "download_selector": "a"}]}`},
+		{"missing base url", `{"sources": [{"name": "a", "result_selector": "a", "download_selector": "a"}]}`},
+		{"missing result selector", `{"sources": [{"name": "a", "base_url": "https://a", "download_selector": "a"}]}`},
+		{"missing download selector", `{"sources": [{"name": "a", "base_url": "https://a", "result_selector": "a"}]}`},
+		{"unknown field", `{"sources": [{"name": "a", "base_url": "https://a", "result_selector": "a", "download_selector": "a", "typo": 1}]}`},
+		{"duplicate name", `{"sources": [
+			{"name": "a", "base_url": "https://a", "result_selector": "a", "download_selector": "a"},
+			{"name": "a", "base_url": "https://b", "result_selector": "a", "download_selector": "a"}
+		]}`},
+		{"collection without item selector", `{"sources": [{"name": "a", "base_url": "https://a", "result_selector": "a", "download_selector": "a", "collection_patterns": ["Collection"]}]}`},
+		{"malformed", `{"sources": [`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := LoadDefinitions(strings.NewReader(tt.input)); err == nil {
+				t.Error("Expected error, got nil")
+			}
+		})
+	}
+}
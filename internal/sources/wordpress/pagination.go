@@ -0,0 +1,218 @@
+package wordpress
+
+import (
+	"context"
+	"ipmanlk/bettercopelk/internal/htmlparser"
+	"ipmanlk/bettercopelk/internal/models"
+	"log"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// maxPageFetches bounds how many results pages a search fetches at once
+const maxPageFetches = 4
+
+// pageNumberPattern finds the page number in WordPress pagination links
+var pageNumberPattern = regexp.MustCompile(`(?:/page/|[?&]paged?=)(\d+)`)
+
+// SearchPages searches the site and calls onPage with the results of every results page.
+// When the number of pages is known from the first page they are fetched concurrently,
+// otherwise the next page links are followed. Only the first page can fail the search,
+// later pages are best effort.
+func (s *Source) SearchPages(ctx context.Context, req models.SearchRequest, onPage func([]models.SearchResult)) error {
+	query := url.QueryEscape(req.Query)
+
+	doc, err := s.fetchDocument(ctx, s.def.BaseURL+strings.ReplaceAll(s.def.SearchPath, "{query}", query))
+	if err != nil {
+		return err
+	}
+
+	pager := &resultPager{
+		seen:       make(map[string]bool),
+		maxResults: s.def.MaxResults,
+		onPage:     onPage,
+	}
+
+	if !s.deliverPage(ctx, pager, doc) || s.def.MaxPages == 1 {
+		return nil
+	}
+
+	if last := s.lastPage(doc); s.def.PagePath != "" && last > 1 {
+		s.fetchPages(ctx, pager, query, min(last, s.def.MaxPages))
+		return nil
+	}
+
+	s.followNextPages(ctx, pager, doc)
+	return nil
+}
+
+// fetchPages fetches pages 2 to last concurrently and delivers them in page order, so
+// results come out the same however the requests finish
+func (s *Source) fetchPages(ctx context.Context, pager *resultPager, query string, last int) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, maxPageFetches)
+
+	// Every fetch sends its page, nil when it failed, so the delivery below never waits forever
+	docs := make([]chan *htmlparser.Document, last+1)
+	for page := 2; page <= last; page++ {
+		docs[page] = make(chan *htmlparser.Document, 1)
+
+		wg.Add(1)
+		go func(page int) {
+			defer wg.Done()
+
+			var doc *htmlparser.Document
+			defer func() { docs[page] <- doc }()
+
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				return
+			}
+
+			replacer := strings.NewReplacer("{query}", query, "{page}", strconv.Itoa(page))
+			fetched, err := s.fetchDocument(ctx, s.def.BaseURL+replacer.Replace(s.def.PagePath))
+			if err != nil {
+				if ctx.Err() == nil {
+					log.Printf("%s: failed to fetch results page %d: %v", s.Name(), page, err)
+				}
+				return
+			}
+			doc = fetched
+		}(page)
+	}
+
+	for page := 2; page <= last; page++ {
+		doc := <-docs[page]
+		if doc == nil {
+			continue
+		}
+		if !s.deliverPage(ctx, pager, doc) {
+			break
+		}
+	}
+
+	cancel()
+	wg.Wait()
+}
+
+// followNextPages fetches one page after another by following the next page link
+func (s *Source) followNextPages(ctx context.Context, pager *resultPager, doc *htmlparser.Document) {
+	if s.def.NextPageSelector == "" {
+		return
+	}
+
+	for page := 2; page <= s.def.MaxPages; page++ {
+		next, exists := doc.Find(s.def.NextPageSelector).First().Attr("href")
+		if !exists || next == "" {
+			return
+		}
+
+		var err error
+		doc, err = s.fetchDocument(ctx, s.absoluteURL(next))
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("%s: failed to fetch results page %d: %v", s.Name(), page, err)
+			}
+			return
+		}
+
+		if !s.deliverPage(ctx, pager, doc) {
+			return
+		}
+	}
+}
+
+// deliverPage passes the new results on a page to the pager and reports whether more are wanted
+func (s *Source) deliverPage(ctx context.Context, pager *resultPager, doc *htmlparser.Document) bool {
+	results, more := pager.take(s.parseSearchResults(doc))
+	s.expandCollections(ctx, results)
+	pager.deliver(results)
+	return more
+}
+
+// lastPage returns the highest page number among the pagination links, 0 when there are none
+func (s *Source) lastPage(doc *htmlparser.Document) int {
+	if s.def.PaginationSelector == "" {
+		return 0
+	}
+
+	last := 0
+	doc.Find(s.def.PaginationSelector).Each(func(i int, e *htmlparser.Element) {
+		page, err := strconv.Atoi(strings.TrimSpace(e.Text()))
+		if err != nil {
+			href, _ := e.Attr("href")
+			if matches := pageNumberPattern.FindStringSubmatch(href); matches != nil {
+				page, _ = strconv.Atoi(matches[1])
+			}
+		}
+		last = max(last, page)
+	})
+
+	return last
+}
+
+func (s *Source) absoluteURL(href string) string {
+	base, err := url.Parse(s.def.BaseURL + "/")
+	if err != nil {
+		return href
+	}
+	ref, err := url.Parse(href)
+	if err != nil {
+		return href
+	}
+	return base.ResolveReference(ref).String()
+}
+
+// resultPager drops results already seen on an earlier page and stops at maxResults
+type resultPager struct {
+	mu         sync.Mutex
+	seen       map[string]bool
+	count      int
+	maxResults int
+
+	deliverMu sync.Mutex
+	onPage    func([]models.SearchResult)
+}
+
+// take returns the results that have not been seen yet and reports whether more are wanted
+func (p *resultPager) take(results []models.SearchResult) ([]models.SearchResult, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var fresh []models.SearchResult
+	for _, result := range results {
+		if p.full() {
+			break
+		}
+		if p.seen[result.URL] {
+			continue
+		}
+		p.seen[result.URL] = true
+		p.count++
+		fresh = append(fresh, result)
+	}
+
+	return fresh, !p.full()
+}
+
+func (p *resultPager) deliver(results []models.SearchResult) {
+	if len(results) == 0 {
+		return
+	}
+
+	p.deliverMu.Lock()
+	defer p.deliverMu.Unlock()
+	p.onPage(results)
+}
+
+func (p *resultPager) full() bool {
+	return p.maxResults > 0 && p.count >= p.maxResults
+}
@@ -0,0 +1,187 @@
+package wordpress
+
+import (
+	"context"
+	"fmt"
+	"ipmanlk/bettercopelk/internal/models"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+// newPaginatedSite serves three results pages with two posts each. The second post of
+// the last page repeats one from the first page.
+func newPaginatedSite(t *testing.T) (*httptest.Server, *sync.Map) {
+	t.Helper()
+
+	requested := &sync.Map{}
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	writePage := func(w http.ResponseWriter, page int) {
+		requested.Store(page, true)
+
+		second := fmt.Sprintf("post-%d-2", page)
+		if page == 3 {
+			second = "post-1-1"
+		}
+		fmt.Fprintf(w, `
+			<div class="item-list">
+				<h2 class="post-box-title"><a href="%[1]s/post-%[2]d-1/">Post %[2]d-1</a></h2>
+				<h2 class="post-box-title"><a href="%[1]s/%[3]s/">Post %[3]s</a></h2>
+			</div>
+			<div class="pagination">
+				<a class="page" href="%[1]s/page/2/?s=batman">2</a>
+				<a class="last" href="%[1]s/page/3/?s=batman">Last »</a>`, server.URL, page, second)
+		if page < 3 {
+			fmt.Fprintf(w, `<span id="tie-next-page"><a href="/page/%d/?s=batman">»</a></span>`, page+1)
+		}
+		fmt.Fprint(w, `</div>`)
+	}
+
+	mux.HandleFunc("GET /", func(w http.ResponseWriter, r *http.Request) {
+		writePage(w, 1)
+	})
+	mux.HandleFunc("GET /page/{page}/", func(w http.ResponseWriter, r *http.Request) {
+		page, _ := strconv.Atoi(r.PathValue("page"))
+		writePage(w, page)
+	})
+
+	return server, requested
+}
+
+func newPaginatedSource(t *testing.T, def Definition) *Source {
+	t.Helper()
+
+	def.Name = "test"
+	def.ResultSelector = ".item-list .post-box-title a"
+	def.DownloadSelector = ".download"
+	if err := def.Validate(); err != nil {
+		t.Fatalf("Validate() failed: %v", err)
+	}
+	return New(def)
+}
+
+func searchTitles(t *testing.T, source *Source) []string {
+	t.Helper()
+
+	results, err := source.Search(context.Background(), models.SearchRequest{Query: "batman"})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+
+	var titles []string
+	for _, result := range results {
+		titles = append(titles, result.Title)
+	}
+	return titles
+}
+
+func TestSource_FollowsNextPageLinks(t *testing.T) {
+	server, _ := newPaginatedSite(t)
+	source := newPaginatedSource(t, Definition{
+		BaseURL:          server.URL,
+		NextPageSelector: "#tie-next-page a",
+	})
+
+	titles := searchTitles(t, source)
+	want := []string{"Post 1-1", "Post post-1-2", "Post 2-1", "Post post-2-2", "Post 3-1"}
+	if fmt.Sprint(titles) != fmt.Sprint(want) {
+		t.Errorf("Search() titles = %v, want %v without the repeated post", titles, want)
+	}
+}
+
+func TestSource_FetchesKnownPagesConcurrently(t *testing.T) {
+	server, requested := newPaginatedSite(t)
+	source := newPaginatedSource(t, Definition{
+		BaseURL:            server.URL,
+		PagePath:           "/page/{page}/?s={query}",
+		PaginationSelector: ".pagination a",
+		MaxPages:           2,
+	})
+
+	titles := searchTitles(t, source)
+	if len(titles) != 4 {
+		t.Errorf("Expected the results of 2 pages, got %v", titles)
+	}
+	if _, fetched := requested.Load(3); fetched {
+		t.Error("Expected the third page not to be fetched with max_pages 2")
+	}
+}
+
+func TestSource_DeliversConcurrentPagesInOrder(t *testing.T) {
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	// The second page only answers once the third has been served
+	thirdServed := make(chan struct{})
+	writePage := func(w http.ResponseWriter, page int) {
+		fmt.Fprintf(w, `
+			<div class="item-list"><h2 class="post-box-title"><a href="%[1]s/post-%[2]d/">Post %[2]d</a></h2></div>
+			<div class="pagination"><a href="%[1]s/page/3/?s=batman">3</a></div>`, server.URL, page)
+	}
+	mux.HandleFunc("GET /", func(w http.ResponseWriter, r *http.Request) {
+		writePage(w, 1)
+	})
+	mux.HandleFunc("GET /page/2/", func(w http.ResponseWriter, r *http.Request) {
+		<-thirdServed
+		writePage(w, 2)
+	})
+	mux.HandleFunc("GET /page/3/", func(w http.ResponseWriter, r *http.Request) {
+		writePage(w, 3)
+		close(thirdServed)
+	})
+
+	source := newPaginatedSource(t, Definition{
+		BaseURL:            server.URL,
+		PagePath:           "/page/{page}/?s={query}",
+		PaginationSelector: ".pagination a",
+	})
+
+	titles := searchTitles(t, source)
+	want := []string{"Post 1", "Post 2", "Post 3"}
+	if fmt.Sprint(titles) != fmt.Sprint(want) {
+		t.Errorf("Search() titles = %v, want %v in page order", titles, want)
+	}
+}
+
+func TestSource_StopsAtMaxResults(t *testing.T) {
+	server, requested := newPaginatedSite(t)
+	source := newPaginatedSource(t, Definition{
+		BaseURL:          server.URL,
+		NextPageSelector: "#tie-next-page a",
+		MaxResults:       3,
+	})
+
+	titles := searchTitles(t, source)
+	if len(titles) != 3 {
+		t.Errorf("Expected 3 results, got %v", titles)
+	}
+	if _, fetched := requested.Load(3); fetched {
+		t.Error("Expected pagination to stop once max_results was reached")
+	}
+}
+
+func TestSource_SearchPagesDeliversEachPage(t *testing.T) {
+	server, _ := newPaginatedSite(t)
+	source := newPaginatedSource(t, Definition{
+		BaseURL:          server.URL,
+		NextPageSelector: "#tie-next-page a",
+	})
+
+	var pages []int
+	err := source.SearchPages(context.Background(), models.SearchRequest{Query: "batman"}, func(results []models.SearchResult) {
+		pages = append(pages, len(results))
+	})
+	if err != nil {
+		t.Fatalf("SearchPages failed: %v", err)
+	}
+
+	if fmt.Sprint(pages) != "[2 2 1]" {
+		t.Errorf("Page sizes = %v, want [2 2 1]", pages)
+	}
+}
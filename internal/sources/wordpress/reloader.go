@@ -0,0 +1,95 @@
+package wordpress
+
+import (
+	"context"
+	"fmt"
+	"ipmanlk/bettercopelk/internal/sources"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// Reloader keeps a Manager in sync with a definitions file on disk
+type Reloader struct {
+	path    string
+	manager *sources.Manager
+
+	mu      sync.Mutex
+	modTime time.Time
+}
+
+func NewReloader(path string, manager *sources.Manager) *Reloader {
+	return &Reloader{
+		path:    path,
+		manager: manager,
+	}
+}
+
+// Reload reads the definitions file and replaces the registered sources.
+// If the file is invalid the currently registered sources are left untouched.
+func (r *Reloader) Reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	info, err := os.Stat(r.path)
+	if err != nil {
+		return fmt.Errorf("failed to stat source definitions: %w", err)
+	}
+
+	// Remember the attempt even if it fails, so a broken file is reported once per edit
+	r.modTime = info.ModTime()
+
+	definitions, err := LoadDefinitionsFile(r.path)
+	if err != nil {
+		return err
+	}
+
+	replacement := make([]sources.Source, 0, len(definitions))
+	for _, def := range definitions {
+		replacement = append(replacement, New(def))
+	}
+
+	r.manager.ReplaceSources(replacement)
+
+	return nil
+}
+
+// Watch polls the definitions file and reloads it whenever it changes, until ctx is
+// cancelled. onReload, when not nil, is called after every successful reload.
+func (r *Reloader) Watch(ctx context.Context, interval time.Duration, onReload func()) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !r.changed() {
+				continue
+			}
+
+			if err := r.Reload(); err != nil {
+				log.Printf("Failed to reload source definitions: %v", err)
+				continue
+			}
+			log.Printf("Reloaded source definitions from %s", r.path)
+			if onReload != nil {
+				onReload()
+			}
+		}
+	}
+}
+
+func (r *Reloader) changed() bool {
+	info, err := os.Stat(r.path)
+	if err != nil {
+		return false
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return !info.ModTime().Equal(r.modTime)
+}
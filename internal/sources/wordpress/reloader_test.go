@@ -0,0 +1,96 @@
+package wordpress
+
+import (
+	"context"
+	"fmt"
+	"ipmanlk/bettercopelk/internal/sources"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const reloaderDefinition = `{"sources": [{
+	"name": "%s",
+	"base_url": "https://example.com",
+	"result_selector": ".post a",
+	"download_selector": ".download"
+}]}`
+
+func writeDefinitions(t *testing.T, path string, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("Failed to write definitions: %v", err)
+	}
+}
+
+func TestReloader_Reload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sources.json")
+	writeDefinitions(t, path, fmt.Sprintf(reloaderDefinition, "first"))
+
+	manager := sources.NewManager()
+	reloader := NewReloader(path, manager)
+
+	if err := reloader.Reload(); err != nil {
+		t.Fatalf("Reload() failed: %v", err)
+	}
+	if _, exists := manager.GetSource("first"); !exists {
+		t.Fatal("Expected source 'first' to be registered")
+	}
+
+	writeDefinitions(t, path, fmt.Sprintf(reloaderDefinition, "second"))
+	if err := reloader.Reload(); err != nil {
+		t.Fatalf("Reload() failed: %v", err)
+	}
+	if _, exists := manager.GetSource("first"); exists {
+		t.Error("Expected source 'first' to be removed after reload")
+	}
+	if _, exists := manager.GetSource("second"); !exists {
+		t.Error("Expected source 'second' to be registered after reload")
+	}
+
+	writeDefinitions(t, path, `{"sources": [`)
+	if err := reloader.Reload(); err == nil {
+		t.Error("Expected error for broken definitions")
+	}
+	if _, exists := manager.GetSource("second"); !exists {
+		t.Error("Expected broken definitions to keep the current sources")
+	}
+}
+
+func TestReloader_Watch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sources.json")
+	writeDefinitions(t, path, fmt.Sprintf(reloaderDefinition, "first"))
+
+	manager := sources.NewManager()
+	reloader := NewReloader(path, manager)
+	if err := reloader.Reload(); err != nil {
+		t.Fatalf("Reload() failed: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	reloaded := make(chan struct{}, 1)
+	go reloader.Watch(ctx, 10*time.Millisecond, func() {
+		select {
+		case reloaded <- struct{}{}:
+		default:
+		}
+	})
+
+	writeDefinitions(t, path, fmt.Sprintf(reloaderDefinition, "second"))
+	// Make sure the modification time moves even on filesystems with coarse timestamps
+	future := time.Now().Add(time.Minute)
+	if err := os.Chtimes(path, future, future); err != nil {
+		t.Fatalf("Failed to update modification time: %v", err)
+	}
+
+	select {
+	case <-reloaded:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected the watcher to reload the changed definitions and call onReload")
+	}
+	if _, exists := manager.GetSource("second"); !exists {
+		t.Error("Expected watcher to pick up the changed definitions")
+	}
+}
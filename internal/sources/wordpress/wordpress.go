@@ -0,0 +1,434 @@
+package wordpress
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"ipmanlk/bettercopelk/internal/htmlparser"
+	"ipmanlk/bettercopelk/internal/models"
+	"ipmanlk/bettercopelk/internal/sources"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxCollectionFetches bounds how many collection posts a search fetches at once
+const maxCollectionFetches = 3
+
+var contentDispositionFilename = regexp.MustCompile(`filename=["']?([^"';]+)["']?`)
+
+// Source is a subtitle source whose scraping rules come from a Definition
+type Source struct {
+	client  *http.Client
+	def     Definition
+	version string
+}
+
+func New(def Definition) *Source {
+	return &Source{
+		client: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+		def:     def,
+		version: definitionVersion(def),
+	}
+}
+
+func (s *Source) Name() string {
+	return s.def.Name
+}
+
+func (s *Source) Priority() int {
+	return s.def.Priority
+}
+
+func (s *Source) Weight() float64 {
+	return s.def.Weight
+}
+
+func (s *Source) WatermarkPatterns() []string {
+	return s.def.WatermarkPatterns
+}
+
+// Definition returns the definition the source was built from
+func (s *Source) Definition() Definition {
+	return s.def
+}
+
+// Version identifies the definition, it changes whenever any field of the definition does
+func (s *Source) Version() string {
+	return s.version
+}
+
+func definitionVersion(def Definition) string {
+	data, _ := json.Marshal(def)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
+
+func (s *Source) IsAvailable() bool {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	req, _ := http.NewRequestWithContext(ctx, "HEAD", s.def.BaseURL, nil)
+	s.setHeaders(req, nil)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return false
+	}
+	defer resp.Body.Close()
+
+	return resp.StatusCode == http.StatusOK
+}
+
+func (s *Source) Search(ctx context.Context, req models.SearchRequest) ([]models.SearchResult, error) {
+	var results []models.SearchResult
+	err := s.SearchPages(ctx, req, func(page []models.SearchResult) {
+		results = append(results, page...)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// expandCollections lists the films on every collection post in results as its children.
+// A collection that cannot be fetched is kept without children, with the reason in
+// ChildrenError, and does not fail the search.
+func (s *Source) expandCollections(ctx context.Context, results []models.SearchResult) {
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, maxCollectionFetches)
+
+	for i := range results {
+		if !results[i].Collection {
+			continue
+		}
+
+		wg.Add(1)
+		go func(result *models.SearchResult) {
+			defer wg.Done()
+
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				return
+			}
+
+			children, err := s.listFilms(ctx, result.URL)
+			if err != nil {
+				result.ChildrenError = fmt.Sprintf("failed to list the films of the collection: %v", err)
+				return
+			}
+			result.Children = children
+		}(&results[i])
+	}
+
+	wg.Wait()
+}
+
+// listFilms returns the films linked from a collection post, each pointing at its own post
+func (s *Source) listFilms(ctx context.Context, postURL string) ([]models.SearchResult, error) {
+	doc, err := s.fetchDocument(ctx, postURL)
+	if err != nil {
+		return nil, err
+	}
+
+	var films []models.SearchResult
+	seen := make(map[string]bool)
+
+	doc.Find(s.def.CollectionItemSelector).Each(func(i int, e *htmlparser.Element) {
+		link := e
+		if _, exists := e.Attr("href"); !exists {
+			link = e.Find("a").First()
+		}
+
+		href, exists := link.Attr("href")
+		if !exists || href == "" {
+			return
+		}
+		filmURL := s.absoluteURL(href)
+		if filmURL == postURL || seen[filmURL] {
+			return
+		}
+
+		titleElement := link
+		if s.def.CollectionTitleSelector != "" {
+			titleElement = e.Find(s.def.CollectionTitleSelector).First()
+		}
+		title := strings.Join(strings.Fields(titleElement.Text()), " ")
+		if title == "" {
+			return
+		}
+		seen[filmURL] = true
+
+		films = append(films, models.SearchResult{
+			Title:  title,
+			URL:    filmURL,
+			Source: s.Name(),
+		})
+	})
+
+	return films, nil
+}
+
+func (s *Source) Download(ctx context.Context, postURL string) ([]byte, string, error) {
+	downloadURL, err := s.getDownloadURL(ctx, postURL)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get download URL: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, s.def.DownloadMethod, downloadURL, nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create download request: %w", err)
+	}
+	s.setHeaders(req, s.def.DownloadHeaders)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to download subtitle: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("download failed: %w", &sources.StatusError{StatusCode: resp.StatusCode})
+	}
+
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read download content: %w", err)
+	}
+
+	filename := s.extractFilename(resp, downloadURL)
+
+	return content, filename, nil
+}
+
+// ListItems returns every download on a post as its own result. Item URLs point back
+// at the post with an #item-N fragment, so Download can pick the right link later.
+func (s *Source) ListItems(ctx context.Context, postURL string) ([]models.SearchResult, error) {
+	pageURL, _ := splitItemURL(postURL)
+
+	doc, err := s.fetchDocument(ctx, pageURL)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []models.SearchResult
+	for i, link := range s.itemLinks(doc) {
+		items = append(items, models.SearchResult{
+			Title:  link.title(i + 1),
+			URL:    fmt.Sprintf("%s#item-%d", pageURL, i+1),
+			Source: s.Name(),
+		})
+	}
+
+	return items, nil
+}
+
+func (s *Source) getDownloadURL(ctx context.Context, postURL string) (string, error) {
+	pageURL, item := splitItemURL(postURL)
+
+	doc, err := s.fetchDocument(ctx, pageURL)
+	if err != nil {
+		return "", err
+	}
+
+	if item > 0 {
+		links := s.itemLinks(doc)
+		if item > len(links) {
+			return "", fmt.Errorf("item %d not found on page", item)
+		}
+		return links[item-1].url, nil
+	}
+
+	downloadLink, exists := doc.Find(s.def.DownloadSelector).First().Attr(s.def.DownloadAttribute)
+	if !exists {
+		return "", fmt.Errorf("download link not found on page")
+	}
+
+	return downloadLink, nil
+}
+
+func (s *Source) fetchDocument(ctx context.Context, pageURL string) (*htmlparser.Document, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", pageURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	s.setHeaders(req, nil)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch page: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, &sources.StatusError{StatusCode: resp.StatusCode}
+	}
+
+	doc, err := htmlparser.NewDocument(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse HTML: %w", err)
+	}
+
+	return doc, nil
+}
+
+// itemLink is a single download link on a post page
+type itemLink struct {
+	url  string
+	text string
+}
+
+// title names the link after its text, falling back to the file name in its URL
+func (l itemLink) title(position int) string {
+	if text := strings.Join(strings.Fields(l.text), " "); text != "" && !strings.EqualFold(text, "download") {
+		return text
+	}
+
+	if parsedURL, err := url.Parse(l.url); err == nil {
+		if filename := path.Base(parsedURL.Path); path.Ext(filename) != "" {
+			return filename
+		}
+	}
+
+	return fmt.Sprintf("Item %d", position)
+}
+
+func (s *Source) itemLinks(doc *htmlparser.Document) []itemLink {
+	var links []itemLink
+	seen := make(map[string]bool)
+
+	doc.Find(s.def.ItemSelector).Each(func(i int, e *htmlparser.Element) {
+		href, exists := e.Attr(s.def.ItemAttribute)
+		if !exists || href == "" || seen[href] {
+			return
+		}
+		seen[href] = true
+
+		text := e.Text()
+		if text == "" {
+			text, _ = e.Attr("title")
+		}
+
+		links = append(links, itemLink{url: href, text: text})
+	})
+
+	return links
+}
+
+// splitItemURL separates the #item-N fragment added by ListItems from a post URL
+func splitItemURL(postURL string) (string, int) {
+	pageURL, fragment, found := strings.Cut(postURL, "#item-")
+	if !found {
+		return postURL, 0
+	}
+
+	item, err := strconv.Atoi(fragment)
+	if err != nil || item < 1 {
+		return postURL, 0
+	}
+	return pageURL, item
+}
+
+func (s *Source) parseSearchResults(doc *htmlparser.Document) []models.SearchResult {
+	var results []models.SearchResult
+
+	doc.Find(s.def.ResultSelector).Each(func(i int, e *htmlparser.Element) {
+		link := e
+		if s.def.LinkSelector != "" {
+			link = e.Find(s.def.LinkSelector).First()
+		}
+
+		url, exists := link.Attr("href")
+		if !exists {
+			return
+		}
+
+		titleElement := link
+		if s.def.TitleSelector != "" {
+			titleElement = e.Find(s.def.TitleSelector).First()
+		}
+
+		title := strings.TrimSpace(titleElement.Text())
+		if title == "" {
+			return
+		}
+
+		if s.shouldIgnore(url, title) {
+			return
+		}
+
+		result := models.SearchResult{
+			Title:  title,
+			URL:    url,
+			Source: s.Name(),
+		}
+		if matchesAny(s.def.SeriesPatterns, url, title) {
+			result.Type = models.TypeTV
+		}
+		result.Collection = matchesAny(s.def.CollectionPatterns, url, title)
+
+		results = append(results, result)
+	})
+
+	return results
+}
+
+func (s *Source) shouldIgnore(url string, title string) bool {
+	return matchesAny(s.def.IgnorePatterns, url, title)
+}
+
+// matchesAny reports whether the title or URL of a result contains any of patterns
+func matchesAny(patterns []string, url string, title string) bool {
+	for _, pattern := range patterns {
+		if strings.Contains(title, pattern) || strings.Contains(url, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Source) setHeaders(req *http.Request, extra map[string]string) {
+	for key, value := range s.def.Headers {
+		req.Header.Set(key, value)
+	}
+	for key, value := range extra {
+		req.Header.Set(key, value)
+	}
+}
+
+func (s *Source) extractFilename(resp *http.Response, downloadURL string) string {
+	if s.def.FilenameHeader != "" {
+		if filename := resp.Header.Get(s.def.FilenameHeader); filename != "" {
+			return filename
+		}
+	}
+
+	if cd := resp.Header.Get("Content-Disposition"); cd != "" {
+		if matches := contentDispositionFilename.FindStringSubmatch(cd); len(matches) > 1 {
+			return matches[1]
+		}
+	}
+
+	parsedURL, err := url.Parse(downloadURL)
+	if err == nil {
+		filename := path.Base(parsedURL.Path)
+		if path.Ext(filename) != "" {
+			return filename
+		}
+	}
+
+	return fmt.Sprintf("%s_subtitle_%d.zip", s.def.Name, time.Now().Unix())
+}
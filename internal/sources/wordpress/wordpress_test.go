@@ -0,0 +1,487 @@
+package wordpress
+
+import (
+	"context"
+	"fmt"
+	"ipmanlk/bettercopelk/internal/models"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestSite(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+
+	mux.HandleFunc("GET /", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("s") != "batman begins" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintf(w, `
+			<div class="item-list">
+				<h2 class="post-box-title"><a href="%[1]s/batman-begins/">Batman Begins (2005)</a></h2>
+				<h2 class="post-box-title"><a href="%[1]s/batman-collection/">Batman Collection</a></h2>
+				<h2 class="post-box-title"><a href="%[1]s/tv_series/batman/">Batman TV</a></h2>
+				<h2 class="post-box-title"><a href="%[1]s/empty/"> </a></h2>
+			</div>`, server.URL)
+	})
+
+	mux.HandleFunc("GET /batman-begins/", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `<a id="btn-download" data-link="%s/files/batman.zip">Download</a>`, server.URL)
+	})
+
+	mux.HandleFunc("GET /batman-collection/", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `
+			<ul id="menu-main"><li><a href="%[1]s/movies/">Movies</a></li></ul>
+			<article class="post-listing post">
+				<h1 class="name post-title entry-title">Batman Collection</h1>
+				<div class="entry">
+					<p>Every Batman film with Sinhala subtitles.</p>
+					<ul>
+						<li><a href="%[1]s/batman-1989-sinhala-subtitles/">Batman (1989)</a></li>
+						<li><a href="%[1]s/batman-returns-1992-sinhala-subtitles/"><strong>Batman Returns</strong> (1992)</a></li>
+						<li><a href="%[1]s/batman-1989-sinhala-subtitles/">Batman (1989)</a></li>
+					</ul>
+					<a id="btn-download" data-link="%[1]s/files/batman-collection.zip">Download</a>
+				</div>
+			</article>
+			<aside class="sidebar"><ul><li><a href="%[1]s/loki/">Loki (2021)</a></li></ul></aside>`, server.URL)
+	})
+
+	mux.HandleFunc("GET /tv_series/batman/", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	})
+
+	mux.HandleFunc("POST /files/batman.zip", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Test") != "download" {
+			http.Error(w, "missing header", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Disposition", `attachment; filename="Batman Begins.zip"`)
+		w.Write([]byte("archive"))
+	})
+
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newTestSource(t *testing.T, baseURL string) *Source {
+	t.Helper()
+
+	def := Definition{
+		Name:              "test",
+		BaseURL:           baseURL,
+		ResultSelector:    ".item-list .post-box-title a",
+		DownloadSelector:  "#btn-download",
+		DownloadAttribute: "data-link",
+		DownloadMethod:    "post",
+		IgnorePatterns:    []string{"Collection", "tv_series"},
+		DownloadHeaders:   map[string]string{"X-Test": "download"},
+	}
+	if err := def.Validate(); err != nil {
+		t.Fatalf("Validate() failed: %v", err)
+	}
+
+	return New(def)
+}
+
+func TestSource_SearchAndDownload(t *testing.T) {
+	server := newTestSite(t)
+	source := newTestSource(t, server.URL)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	results, err := source.Search(ctx, models.SearchRequest{Query: "batman begins"})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+
+	if len(results) != 1 {
+		t.Fatalf("Expected 1 result after ignore patterns, got %d: %+v", len(results), results)
+	}
+
+	result := results[0]
+	if result.Title != "Batman Begins (2005)" || result.Source != "test" || result.URL != server.URL+"/batman-begins/" {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+
+	content, filename, err := source.Download(ctx, result.URL)
+	if err != nil {
+		t.Fatalf("Download failed: %v", err)
+	}
+	if string(content) != "archive" {
+		t.Errorf("Download content = %q, want %q", content, "archive")
+	}
+	if filename != "Batman Begins.zip" {
+		t.Errorf("Download filename = %q, want %q", filename, "Batman Begins.zip")
+	}
+}
+
+func TestSource_ExpandsCollections(t *testing.T) {
+	server := newTestSite(t)
+
+	def := Definition{
+		Name:                   "test",
+		BaseURL:                server.URL,
+		ResultSelector:         ".item-list .post-box-title a",
+		DownloadSelector:       "#btn-download",
+		CollectionPatterns:     []string{"Collection"},
+		CollectionItemSelector: ".entry li a",
+		IgnorePatterns:         []string{"tv_series"},
+	}
+	if err := def.Validate(); err != nil {
+		t.Fatalf("Validate() failed: %v", err)
+	}
+	source := New(def)
+
+	results, err := source.Search(context.Background(), models.SearchRequest{Query: "batman begins"})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("Expected the film and the collection, got %+v", results)
+	}
+
+	if results[0].Collection || results[0].Children != nil {
+		t.Errorf("Expected a plain film first, got %+v", results[0])
+	}
+
+	collection := results[1]
+	if !collection.Collection {
+		t.Fatalf("Expected a collection, got %+v", collection)
+	}
+
+	want := []models.SearchResult{
+		{Title: "Batman (1989)", URL: server.URL + "/batman-1989-sinhala-subtitles/", Source: "test"},
+		{Title: "Batman Returns (1992)", URL: server.URL + "/batman-returns-1992-sinhala-subtitles/", Source: "test"},
+	}
+	if !reflect.DeepEqual(collection.Children, want) {
+		t.Errorf("Children = %+v, want %+v", collection.Children, want)
+	}
+}
+
+func TestSource_ReportsFailedCollections(t *testing.T) {
+	server := newTestSite(t)
+
+	def := Definition{
+		Name:                   "test",
+		BaseURL:                server.URL,
+		ResultSelector:         ".item-list .post-box-title a",
+		DownloadSelector:       "#btn-download",
+		CollectionPatterns:     []string{"tv_series"},
+		CollectionItemSelector: ".entry li a",
+		IgnorePatterns:         []string{"Collection"},
+	}
+	if err := def.Validate(); err != nil {
+		t.Fatalf("Validate() failed: %v", err)
+	}
+	source := New(def)
+
+	results, err := source.Search(context.Background(), models.SearchRequest{Query: "batman begins"})
+	if err != nil {
+		t.Fatalf("Expected a failed collection not to fail the search, got %v", err)
+	}
+	if len(results) != 2 || !results[1].Collection || results[1].Children != nil {
+		t.Fatalf("Expected the results with the failed collection left unexpanded, got %+v", results)
+	}
+	if !strings.Contains(results[1].ChildrenError, "503") || results[0].ChildrenError != "" {
+		t.Errorf("Expected the reason on the failed collection only, got %+v", results)
+	}
+}
+
+func TestDefaultDefinitions_CollectionFilms(t *testing.T) {
+	// Trimmed collection posts as each site's theme renders them
+	pages := map[string]string{
+		"baiscopelk": `
+			<div class="elementor-element elementor-widget elementor-widget-theme-post-content">
+				<div class="elementor-widget-container">
+					<p>Harry Potter films with Sinhala subtitles.</p>
+					<ul>
+						<li><a href="{base}/harry-potter-and-the-philosophers-stone-2001-sinhala-subtitles/">Harry Potter and the Philosopher's Stone (2001)</a></li>
+						<li><a href="{base}/harry-potter-and-the-chamber-of-secrets-2002-sinhala-subtitles/">Harry Potter and the Chamber of Secrets (2002)</a></li>
+					</ul>
+				</div>
+			</div>
+			<div class="elementor-widget-button">
+				<a class="elementor-button" href="{base}/download/1234/" data-e-disable-page-transition="true">Download</a>
+			</div>`,
+		"cineru": `
+			<div class="entry">
+				<ul>
+					<li><a href="{base}/harry-potter-and-the-philosophers-stone-2001-sinhala-subtitles/">Harry Potter and the Philosopher's Stone (2001)</a></li>
+					<li><a href="{base}/harry-potter-and-the-chamber-of-secrets-2002-sinhala-subtitles/">Harry Potter and the Chamber of Secrets (2002)</a></li>
+				</ul>
+				<a id="btn-download" data-link="{base}/files/harry-potter.zip">Download</a>
+			</div>`,
+		"piratelk": `
+			<div class="entry">
+				<ul>
+					<li><a href="{base}/harry-potter-and-the-philosophers-stone-2001-sinhala-subtitles/">Harry Potter and the Philosopher's Stone (2001)</a></li>
+					<li><a href="{base}/harry-potter-and-the-chamber-of-secrets-2002-sinhala-subtitles/">Harry Potter and the Chamber of Secrets (2002)</a></li>
+				</ul>
+				<a class="download-button" href="{base}/files/harry-potter.zip">Download</a>
+			</div>`,
+		"zoomlk": `
+			<div class="td-post-content tagdiv-type">
+				<ul>
+					<li><a href="{base}/harry-potter-and-the-philosophers-stone-2001-sinhala-subtitles/">Harry Potter and the Philosopher's Stone (2001)</a></li>
+					<li><a href="{base}/harry-potter-and-the-chamber-of-secrets-2002-sinhala-subtitles/">Harry Potter and the Chamber of Secrets (2002)</a></li>
+				</ul>
+				<a class="download-button" href="{base}/files/harry-potter.zip">Download</a>
+			</div>`,
+	}
+
+	definitions, err := DefaultDefinitions()
+	if err != nil {
+		t.Fatalf("DefaultDefinitions() failed: %v", err)
+	}
+
+	for _, def := range definitions {
+		t.Run(def.Name, func(t *testing.T) {
+			var server *httptest.Server
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, strings.ReplaceAll(pages[def.Name], "{base}", server.URL))
+			}))
+			defer server.Close()
+
+			def.BaseURL = server.URL
+			films, err := New(def).listFilms(context.Background(), server.URL+"/harry-potter-collection/")
+			if err != nil {
+				t.Fatalf("listFilms failed: %v", err)
+			}
+
+			var titles []string
+			for _, film := range films {
+				titles = append(titles, film.Title)
+			}
+			want := []string{"Harry Potter and the Philosopher's Stone (2001)", "Harry Potter and the Chamber of Secrets (2002)"}
+			if !reflect.DeepEqual(titles, want) {
+				t.Errorf("Film titles = %q, want %q", titles, want)
+			}
+		})
+	}
+}
+
+func TestSource_LinkAndTitleSelectors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `
+			<article class="elementor-post">
+				<a class="elementor-post__thumbnail__link" href="https://example.com/post1">Thumbnail</a>
+				<h5 class="elementor-post__title"><a href="https://example.com/post1">Post Title 1</a></h5>
+			</article>`)
+	}))
+	defer server.Close()
+
+	source := New(Definition{
+		Name:           "elementor",
+		BaseURL:        server.URL,
+		SearchPath:     "/search/{query}",
+		ResultSelector: "article.elementor-post",
+		LinkSelector:   "a.elementor-post__thumbnail__link, h5.elementor-post__title a",
+		TitleSelector:  "h5.elementor-post__title",
+	})
+
+	results, err := source.Search(context.Background(), models.SearchRequest{Query: "post"})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+
+	if len(results) != 1 || results[0].Title != "Post Title 1" || results[0].URL != "https://example.com/post1" {
+		t.Errorf("Unexpected results: %+v", results)
+	}
+}
+
+func TestSource_SeriesItems(t *testing.T) {
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	mux.HandleFunc("GET /", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `
+			<div class="item-list">
+				<h2 class="post-box-title"><a href="%[1]s/tv_series/loki/">Loki Season 1</a></h2>
+				<h2 class="post-box-title"><a href="%[1]s/loki-movie/">Loki (2021)</a></h2>
+			</div>`, server.URL)
+	})
+	mux.HandleFunc("GET /tv_series/loki/", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `
+			<a class="episode" href="%[1]s/files/loki-s01e01.zip">Loki S01E01</a>
+			<a class="episode" href="%[1]s/files/loki-s01e01.zip">Loki S01E01</a>
+			<a class="episode" href="%[1]s/files/loki-s01e02.zip">Download</a>`, server.URL)
+	})
+	mux.HandleFunc("GET /files/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.URL.Path))
+	})
+
+	def := Definition{
+		Name:             "test",
+		BaseURL:          server.URL,
+		ResultSelector:   ".item-list .post-box-title a",
+		DownloadSelector: ".download",
+		SeriesPatterns:   []string{"tv_series"},
+		ItemSelector:     "a.episode",
+	}
+	if err := def.Validate(); err != nil {
+		t.Fatalf("Validate() failed: %v", err)
+	}
+	source := New(def)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	results, err := source.Search(ctx, models.SearchRequest{Query: "loki"})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(results) != 2 || results[0].Type != models.TypeTV || results[1].Type != "" {
+		t.Fatalf("Expected only the series post flagged as tv, got %+v", results)
+	}
+
+	items, err := source.ListItems(ctx, results[0].URL)
+	if err != nil {
+		t.Fatalf("ListItems failed: %v", err)
+	}
+	if len(items) != 2 {
+		t.Fatalf("Expected 2 distinct items, got %d: %+v", len(items), items)
+	}
+	if items[0].Title != "Loki S01E01" || items[1].Title != "loki-s01e02.zip" {
+		t.Errorf("Unexpected item titles: %+v", items)
+	}
+	if items[1].URL != results[0].URL+"#item-2" {
+		t.Errorf("Item URL = %q, want the post URL with an item fragment", items[1].URL)
+	}
+
+	content, filename, err := source.Download(ctx, items[1].URL)
+	if err != nil {
+		t.Fatalf("Download failed: %v", err)
+	}
+	if string(content) != "/files/loki-s01e02.zip" || filename != "loki-s01e02.zip" {
+		t.Errorf("Download() = %q, %q, want the second item", content, filename)
+	}
+
+	if _, _, err := source.Download(ctx, results[0].URL+"#item-3"); err == nil {
+		t.Error("Expected an error for a missing item")
+	}
+}
+
+func TestSource_ExtractFilename(t *testing.T) {
+	source := New(Definition{Name: "test", FilenameHeader: "X-Dlm-File-Name"})
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+		url     string
+		want    string
+	}{
+		{"filename header", map[string]string{"X-Dlm-File-Name": "a.zip", "Content-Disposition": `attachment; filename="b.zip"`}, "https://x/c.zip", "a.zip"},
+		{"content disposition", map[string]string{"Content-Disposition": `attachment; filename="b.zip"`}, "https://x/c.zip", "b.zip"},
+		{"url path", nil, "https://x/files/c.zip", "c.zip"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{Header: http.Header{}}
+			for key, value := range tt.headers {
+				resp.Header.Set(key, value)
+			}
+			if got := source.extractFilename(resp, tt.url); got != tt.want {
+				t.Errorf("extractFilename() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// The following tests hit the real sites and skip when a site is unreachable
+
+func TestDefaultSources_IsAvailable(t *testing.T) {
+	definitions, err := DefaultDefinitions()
+	if err != nil {
+		t.Fatalf("DefaultDefinitions() failed: %v", err)
+	}
+
+	for _, def := range definitions {
+		t.Run(def.Name, func(t *testing.T) {
+			// We don't assert true/false because the website might be temporarily unavailable
+			source := New(def)
+			t.Logf("%s availability: %v", def.Name, source.IsAvailable())
+		})
+	}
+}
+
+func TestDefaultSources_SearchAndDownload(t *testing.T) {
+	definitions, err := DefaultDefinitions()
+	if err != nil {
+		t.Fatalf("DefaultDefinitions() failed: %v", err)
+	}
+
+	for _, def := range definitions {
+		t.Run(def.Name, func(t *testing.T) {
+			source := New(def)
+
+			if !source.IsAvailable() {
+				t.Skipf("%s is not available, skipping search test", def.Name)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+			defer cancel()
+
+			results, err := source.Search(ctx, models.SearchRequest{Query: "batman"})
+			if err != nil {
+				t.Fatalf("Search failed: %v", err)
+			}
+
+			if len(results) == 0 {
+				t.Fatal("Expected at least one search result, got none")
+			}
+
+			result := results[0]
+			if result.Title == "" {
+				t.Error("First result title is empty")
+			}
+			if result.URL == "" {
+				t.Error("First result URL is empty")
+			}
+			if result.Source != def.Name {
+				t.Errorf("First result source = %v, want %v", result.Source, def.Name)
+			}
+
+			t.Logf("Found %d results for 'batman'", len(results))
+			t.Logf("Testing download from URL: %s", result.URL)
+
+			content, filename, err := source.Download(ctx, result.URL)
+			if err != nil {
+				t.Fatalf("Download failed: %v", err)
+			}
+
+			if filename == "" {
+				t.Error("Filename is empty")
+			}
+
+			if len(content) < 100 {
+				t.Errorf("Downloaded content seems too small: %d bytes", len(content))
+			}
+
+			t.Logf("Downloaded file: %s (%d bytes)", filename, len(content))
+		})
+	}
+}
+
+func TestSource_VersionFollowsDefinition(t *testing.T) {
+	def := Definition{Name: "a", BaseURL: "https://a", ResultSelector: ".post a", DownloadSelector: ".download"}
+	first := New(def)
+
+	if New(def).Version() != first.Version() {
+		t.Error("Expected the same definition to keep its version")
+	}
+	def.ResultSelector = ".entry-title a"
+	if New(def).Version() == first.Version() {
+		t.Error("Expected a changed definition to get a new version")
+	}
+}
@@ -343,6 +343,11 @@ function setupSearchResultListeners(eventSource, resultsReceived) {
     const sourceData = JSON.parse(event.data);
     handleSourceComplete(sourceData);
   });
+
+  eventSource.addEventListener('source-error', function(event) {
+    const sourceData = JSON.parse(event.data);
+    handleSourceError(sourceData);
+  });
   
   eventSource.addEventListener('error', function(event) {
     logToConsole(`Error in stream: ${event.data || 'Connection error'}`, true);
@@ -364,6 +369,15 @@ function addResultItem(result) {
     <div class="result-title" data-url="${result.url}" data-source="${result.source}" onclick="downloadSubtitle(this)">${result.title}</div>
     <div class="result-source">Source: ${result.source}</div>
   `;
+
+  if (result.children && result.children.length > 0) {
+    const children = document.createElement('ul');
+    children.className = 'result-children';
+    children.innerHTML = result.children.map((child) => `
+      <li class="result-title" data-url="${child.url}" data-source="${child.source}" onclick="downloadSubtitle(this)">${child.title}</li>
+    `).join('');
+    resultItem.appendChild(children);
+  }
   
   elements.resultsList.appendChild(resultItem);
 }
@@ -414,6 +428,20 @@ function handleSourceComplete(sourceData) {
   }
 }
 
+/**
+ * Handle a failed or timed out source search
+ * @param {Object} sourceData - Status of the failed source
+ */
+function handleSourceError(sourceData) {
+  const source = sourceData.source;
+  logToConsole(`${source} search ${sourceData.status}: ${sourceData.error}`, true);
+
+  const badge = document.getElementById(`source-badge-${source}`);
+  if (badge) {
+    badge.classList.add("failed");
+  }
+}
+
 /**
  * Handle search completion
  * @param {number} resultsCount - Total results found
@@ -0,0 +1,302 @@
+package subtitle
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	assStyleFields = []string{"Name", "Fontname", "Fontsize", "PrimaryColour", "SecondaryColour", "OutlineColour", "BackColour",
+		"Bold", "Italic", "Underline", "StrikeOut", "ScaleX", "ScaleY", "Spacing", "Angle", "BorderStyle", "Outline", "Shadow",
+		"Alignment", "MarginL", "MarginR", "MarginV", "Encoding"}
+	ssaStyleFields = []string{"Name", "Fontname", "Fontsize", "PrimaryColour", "SecondaryColour", "TertiaryColour", "BackColour",
+		"Bold", "Italic", "BorderStyle", "Outline", "Shadow", "Alignment", "MarginL", "MarginR", "MarginV", "AlphaLevel", "Encoding"}
+	assEventFields = []string{"Layer", "Start", "End", "Style", "Name", "MarginL", "MarginR", "MarginV", "Effect", "Text"}
+
+	// defaultStyle is the style written when a file has none, in ASS terms
+	defaultStyle = map[string]string{
+		"Name": "Default", "Fontname": "Arial", "Fontsize": "20", "PrimaryColour": "&H00FFFFFF", "SecondaryColour": "&H000000FF",
+		"OutlineColour": "&H00000000", "BackColour": "&H00000000", "Bold": "0", "Italic": "0", "Underline": "0", "StrikeOut": "0",
+		"ScaleX": "100", "ScaleY": "100", "Spacing": "0", "Angle": "0", "BorderStyle": "1", "Outline": "2", "Shadow": "2",
+		"Alignment": "2", "MarginL": "10", "MarginR": "10", "MarginV": "10", "Encoding": "1", "AlphaLevel": "0",
+	}
+
+	assOverridePattern = regexp.MustCompile(`\{[^}]*\}`)
+	assTagPattern      = regexp.MustCompile(`\\(an|a)(\d+)|\\([ibu])([01])`)
+	assStylingPattern  = regexp.MustCompile(`</?[ibu]>`)
+)
+
+func parseASS(text string) *Subtitle {
+	s := &Subtitle{}
+	section := ""
+	ssa := false
+	var styleFormat, eventFormat []string
+
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.ToLower(line)
+			if section == "[v4 styles]" {
+				ssa = true
+			}
+			continue
+		}
+
+		key, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		value = strings.TrimSpace(value)
+
+		switch {
+		case key == "Format" && strings.HasSuffix(section, "styles]"):
+			styleFormat = splitFormat(value)
+		case key == "Format" && section == "[events]":
+			eventFormat = splitFormat(value)
+		case key == "Style":
+			if styleFormat == nil {
+				styleFormat = assStyleFields
+				if ssa {
+					styleFormat = ssaStyleFields
+				}
+			}
+			s.Styles = append(s.Styles, parseStyle(styleFormat, value, ssa))
+		case key == "Dialogue":
+			if eventFormat == nil {
+				eventFormat = assEventFields
+			}
+			if cue, ok := parseDialogue(eventFormat, value, ssa); ok {
+				s.Cues = append(s.Cues, cue)
+			}
+		}
+	}
+
+	return s
+}
+
+func splitFormat(value string) []string {
+	fields := strings.Split(value, ",")
+	for i := range fields {
+		fields[i] = strings.TrimSpace(fields[i])
+	}
+	return fields
+}
+
+// parseStyle reads a style line, converting SSA colours and alignments to ASS terms
+func parseStyle(format []string, value string, ssa bool) Style {
+	values := strings.SplitN(value, ",", len(format))
+	style := Style{Fields: make(map[string]string, len(format))}
+	for i, field := range format {
+		if i < len(values) {
+			style.Fields[field] = strings.TrimSpace(values[i])
+		}
+	}
+
+	if ssa {
+		style.Fields["OutlineColour"] = style.Fields["TertiaryColour"]
+		delete(style.Fields, "TertiaryColour")
+		for _, field := range []string{"PrimaryColour", "SecondaryColour", "OutlineColour", "BackColour"} {
+			if n, err := strconv.ParseInt(style.Fields[field], 10, 64); err == nil {
+				style.Fields[field] = fmt.Sprintf("&H%08X", n)
+			}
+		}
+		if n, err := strconv.Atoi(style.Fields["Alignment"]); err == nil {
+			style.Fields["Alignment"] = strconv.Itoa(int(fromSSAAlignment(n)))
+		}
+	}
+
+	style.Name = style.Fields["Name"]
+	return style
+}
+
+func parseDialogue(format []string, value string, ssa bool) (Cue, bool) {
+	values := strings.SplitN(value, ",", len(format))
+	if len(values) < len(format) {
+		return Cue{}, false
+	}
+
+	var cue Cue
+	var startOK, endOK bool
+	for i, field := range format {
+		switch field {
+		case "Start":
+			cue.Start, startOK = ParseTimestamp(values[i])
+		case "End":
+			cue.End, endOK = ParseTimestamp(values[i])
+		case "Style":
+			cue.Style = strings.TrimPrefix(strings.TrimSpace(values[i]), "*")
+		case "Text":
+			cue.Lines, cue.Position = parseASSText(values[i], ssa)
+		}
+	}
+
+	return cue, startOK && endOK
+}
+
+// parseASSText turns the override tags of a dialogue into styling tags and a position
+func parseASSText(text string, ssa bool) ([]string, Alignment) {
+	position := AlignDefault
+
+	text = assOverridePattern.ReplaceAllStringFunc(text, func(block string) string {
+		var tags strings.Builder
+		for _, m := range assTagPattern.FindAllStringSubmatch(block, -1) {
+			switch {
+			case m[1] == "an":
+				n, _ := strconv.Atoi(m[2])
+				position = Alignment(n)
+			case m[1] == "a":
+				n, _ := strconv.Atoi(m[2])
+				position = fromSSAAlignment(n)
+			case m[4] == "1":
+				tags.WriteString("<" + m[3] + ">")
+			default:
+				tags.WriteString("</" + m[3] + ">")
+			}
+		}
+		return tags.String()
+	})
+
+	text = strings.ReplaceAll(text, `\h`, " ")
+	text = strings.ReplaceAll(text, `\n`, `\N`)
+	if position < 0 || position > 9 {
+		position = AlignDefault
+	}
+	return strings.Split(text, `\N`), position
+}
+
+// fromSSAAlignment converts SSA alignments, 1-3 at the bottom, 5-7 at the top and 9-11
+// in the middle, to the numeric keypad layout
+func fromSSAAlignment(n int) Alignment {
+	switch {
+	case n >= 9 && n <= 11:
+		return Alignment(n - 5)
+	case n >= 5 && n <= 7:
+		return Alignment(n + 2)
+	case n >= 1 && n <= 3:
+		return Alignment(n)
+	default:
+		return AlignDefault
+	}
+}
+
+func toSSAAlignment(a Alignment) int {
+	switch {
+	case a >= 7:
+		return int(a) - 2
+	case a >= 4:
+		return int(a) + 5
+	default:
+		return int(a)
+	}
+}
+
+func writeASS(s *Subtitle, ssa bool) []byte {
+	styleFields, scriptType, stylesSection := assStyleFields, "v4.00+", "[V4+ Styles]"
+	if ssa {
+		styleFields, scriptType, stylesSection = ssaStyleFields, "v4.00", "[V4 Styles]"
+	}
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "[Script Info]\nScriptType: %s\nWrapStyle: 0\nPlayResX: 384\nPlayResY: 288\n\n", scriptType)
+
+	fmt.Fprintf(&out, "%s\nFormat: %s\n", stylesSection, strings.Join(styleFields, ", "))
+	styles := s.Styles
+	if len(styles) == 0 {
+		styles = []Style{{Name: "Default", Fields: defaultStyle}}
+	}
+	for _, style := range styles {
+		fmt.Fprintf(&out, "Style: %s\n", strings.Join(styleValues(style, styleFields, ssa), ","))
+	}
+
+	out.WriteString("\n[Events]\nFormat: ")
+	if ssa {
+		out.WriteString("Marked, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text\n")
+	} else {
+		out.WriteString(strings.Join(assEventFields, ", ") + "\n")
+	}
+
+	for _, cue := range s.Cues {
+		style := cue.Style
+		if style == "" {
+			style = styles[0].Name
+		}
+		layer := "0"
+		if ssa {
+			layer = "Marked=0"
+		}
+		fmt.Fprintf(&out, "Dialogue: %s,%s,%s,%s,,0,0,0,,%s\n", layer, assTimestamp(cue.Start), assTimestamp(cue.End), style, assText(cue, ssa))
+	}
+
+	return []byte(out.String())
+}
+
+// styleValues returns the values of a style in the order of fields, converting colours
+// and alignments back to SSA terms for SSA files
+func styleValues(style Style, fields []string, ssa bool) []string {
+	values := make([]string, len(fields))
+	for i, field := range fields {
+		name := field
+		if ssa && field == "TertiaryColour" {
+			name = "OutlineColour"
+		}
+
+		value, ok := style.Fields[name]
+		if !ok || value == "" {
+			value = defaultStyle[name]
+		}
+
+		if ssa {
+			switch name {
+			case "PrimaryColour", "SecondaryColour", "OutlineColour", "BackColour":
+				if n, err := strconv.ParseInt(strings.TrimPrefix(strings.ToUpper(value), "&H"), 16, 64); err == nil {
+					value = strconv.FormatInt(n&0xFFFFFF, 10)
+				}
+			case "Alignment":
+				if n, err := strconv.Atoi(value); err == nil {
+					value = strconv.Itoa(toSSAAlignment(Alignment(n)))
+				}
+			}
+		}
+		values[i] = value
+	}
+	values[0] = style.Name
+	return values
+}
+
+// assText writes the lines of a cue with override tags for styling and position
+func assText(cue Cue, ssa bool) string {
+	var text strings.Builder
+	if cue.Position != AlignDefault && cue.Position != AlignBottom {
+		if ssa {
+			fmt.Fprintf(&text, "{\\a%d}", toSSAAlignment(cue.Position))
+		} else {
+			fmt.Fprintf(&text, "{\\an%d}", cue.Position)
+		}
+	}
+
+	for i, line := range cue.Lines {
+		if i > 0 {
+			text.WriteString(`\N`)
+		}
+		line = assStylingPattern.ReplaceAllStringFunc(stripTags(line), func(tag string) string {
+			if strings.HasPrefix(tag, "</") {
+				return `{\` + tag[2:3] + `0}`
+			}
+			return `{\` + tag[1:2] + `1}`
+		})
+		text.WriteString(line)
+	}
+	return text.String()
+}
+
+// assTimestamp writes d as h:mm:ss.cc
+func assTimestamp(d time.Duration) string {
+	if d < 0 {
+		d = 0
+	}
+	cs := d.Round(10*time.Millisecond).Milliseconds() / 10
+	return fmt.Sprintf("%d:%02d:%02d.%02d", cs/360000, cs/6000%60, cs/100%60, cs%100)
+}
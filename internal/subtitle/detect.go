@@ -0,0 +1,89 @@
+// Package subtitle works with the subtitle file formats found on Sinhala subtitle sites
+package subtitle
+
+import (
+	"bufio"
+	"bytes"
+	"path"
+	"regexp"
+	"strings"
+)
+
+const (
+	FormatSRT      = "srt"
+	FormatVTT      = "vtt"
+	FormatASS      = "ass"
+	FormatSSA      = "ssa"
+	FormatMicroDVD = "microdvd"
+)
+
+var (
+	srtTimingPattern = regexp.MustCompile(`\d+:\d{2}:\d{2}[,.]\d+\s*-->\s*\d+:\d{2}:\d{2}[,.]\d+`)
+	vttTimingPattern = regexp.MustCompile(`(?:\d+:)?\d{2}:\d{2}\.\d+\s*-->\s*(?:\d+:)?\d{2}:\d{2}\.\d+`)
+	microDVDPattern  = regexp.MustCompile(`^\{\d+\}\{\d*\}`)
+)
+
+// DetectFormat returns the format of a UTF-8 subtitle file from its content, falling back
+// to the extension of name. It returns "" when the format is not recognised.
+func DetectFormat(name string, data []byte) string {
+	data = bytes.TrimPrefix(data, []byte{0xEF, 0xBB, 0xBF})
+	trimmed := bytes.TrimSpace(data)
+
+	switch {
+	case bytes.HasPrefix(trimmed, []byte("WEBVTT")):
+		return FormatVTT
+	case bytes.Contains(data, []byte("[Script Info]")) || bytes.Contains(data, []byte("[Events]")):
+		if bytes.Contains(data, []byte("[V4+ Styles]")) || strings.EqualFold(path.Ext(name), ".ass") {
+			return FormatASS
+		}
+		return FormatSSA
+	case microDVDPattern.Match(trimmed):
+		return FormatMicroDVD
+	case srtTimingPattern.Match(data):
+		return FormatSRT
+	}
+
+	switch strings.ToLower(path.Ext(name)) {
+	case ".srt":
+		return FormatSRT
+	case ".vtt":
+		return FormatVTT
+	case ".ass":
+		return FormatASS
+	case ".ssa":
+		return FormatSSA
+	default:
+		return ""
+	}
+}
+
+// CountCues counts the cues of a UTF-8 subtitle file in the given format by their timing lines
+func CountCues(format string, data []byte) int {
+	count := 0
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch format {
+		case FormatSRT:
+			if srtTimingPattern.MatchString(line) {
+				count++
+			}
+		case FormatVTT:
+			if vttTimingPattern.MatchString(line) {
+				count++
+			}
+		case FormatASS, FormatSSA:
+			if strings.HasPrefix(line, "Dialogue:") {
+				count++
+			}
+		case FormatMicroDVD:
+			if microDVDPattern.MatchString(line) {
+				count++
+			}
+		}
+	}
+
+	return count
+}
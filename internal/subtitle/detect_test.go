@@ -0,0 +1,33 @@
+package subtitle
+
+import "testing"
+
+func TestDetectFormatAndCountCues(t *testing.T) {
+	tests := []struct {
+		name   string
+		file   string
+		data   string
+		format string
+		cues   int
+	}{
+		{"srt", "a.srt", "1\r\n00:00:01,000 --> 00:00:02,000\r\nHi\r\n\r\n2\r\n00:00:03,000 --> 00:00:04,500\r\nThere\r\n", FormatSRT, 2},
+		{"srt named txt", "a.txt", "1\n00:00:01,000 --> 00:00:02,000\nHi\n", FormatSRT, 1},
+		{"vtt", "a.vtt", "WEBVTT\n\n00:01.000 --> 00:02.000\nHi\n\n01:00:03.000 --> 01:00:04.000\nThere\n", FormatVTT, 2},
+		{"ass", "a.ass", "[Script Info]\nScriptType: v4.00+\n\n[V4+ Styles]\n\n[Events]\nDialogue: 0,0:00:01.00,0:00:02.00,Default,,0,0,0,,Hi\n", FormatASS, 1},
+		{"ssa", "a.ssa", "[Script Info]\nScriptType: v4.00\n\n[V4 Styles]\n\n[Events]\nDialogue: Marked=0,0:00:01.00,0:00:02.00,Default,,0,0,0,,Hi\n", FormatSSA, 1},
+		{"microdvd", "a.sub", "{1}{1}23.976\n{24}{48}Hi\n{72}{96}There|Second line\n", FormatMicroDVD, 3},
+		{"unknown", "a.sub", "\x00\x00\x01\xba binary", "", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			format := DetectFormat(tt.file, []byte(tt.data))
+			if format != tt.format {
+				t.Fatalf("DetectFormat() = %q, want %q", format, tt.format)
+			}
+			if cues := CountCues(format, []byte(tt.data)); cues != tt.cues {
+				t.Errorf("CountCues() = %d, want %d", cues, tt.cues)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package subtitle
+
+import (
+	"maps"
+	"sort"
+	"time"
+)
+
+// Style names used by Stack
+const (
+	PrimaryStyle   = "Primary"
+	SecondaryStyle = "Secondary"
+)
+
+// Merge returns a subtitle with the lines of every secondary cue added under the primary
+// cue it overlaps the most, so both languages show together in players that draw one cue
+// at a time. Secondary cues that overlap no primary cue are kept on their own.
+func Merge(primary, secondary *Subtitle) *Subtitle {
+	merged := &Subtitle{FrameRate: primary.FrameRate}
+	merged.Cues = make([]Cue, len(primary.Cues))
+	for i, cue := range primary.Cues {
+		cue.Lines = append([]string(nil), cue.Lines...)
+		cue.Style, cue.Position = "", AlignDefault
+		merged.Cues[i] = cue
+	}
+
+	var unmatched []Cue
+	for _, cue := range secondary.Cues {
+		best, bestOverlap := -1, time.Duration(0)
+		for i, candidate := range primary.Cues {
+			if overlap := min(cue.End, candidate.End) - max(cue.Start, candidate.Start); overlap > bestOverlap {
+				best, bestOverlap = i, overlap
+			}
+		}
+
+		if best < 0 {
+			cue.Style, cue.Position = "", AlignDefault
+			unmatched = append(unmatched, cue)
+			continue
+		}
+		merged.Cues[best].Lines = append(merged.Cues[best].Lines, cue.Lines...)
+	}
+
+	merged.Cues = append(merged.Cues, unmatched...)
+	sortCues(merged.Cues)
+	return merged
+}
+
+// Stack returns a subtitle for ASS/SSA showing the primary cues at the bottom of the screen
+// and the secondary cues at the top, each in its own style
+func Stack(primary, secondary *Subtitle) *Subtitle {
+	primaryStyle := Style{Name: PrimaryStyle, Fields: maps.Clone(defaultStyle)}
+	secondaryStyle := Style{Name: SecondaryStyle, Fields: maps.Clone(defaultStyle)}
+	secondaryStyle.Fields["Alignment"] = "8"
+	secondaryStyle.Fields["Fontsize"] = "16"
+
+	stacked := &Subtitle{
+		FrameRate: primary.FrameRate,
+		Styles:    []Style{primaryStyle, secondaryStyle},
+	}
+	for _, cue := range primary.Cues {
+		cue.Style, cue.Position = PrimaryStyle, AlignDefault
+		stacked.Cues = append(stacked.Cues, cue)
+	}
+	for _, cue := range secondary.Cues {
+		cue.Style, cue.Position = SecondaryStyle, AlignDefault
+		stacked.Cues = append(stacked.Cues, cue)
+	}
+
+	sortCues(stacked.Cues)
+	return stacked
+}
+
+func sortCues(cues []Cue) {
+	sort.SliceStable(cues, func(i, j int) bool {
+		return cues[i].Start < cues[j].Start
+	})
+}
@@ -0,0 +1,61 @@
+package subtitle
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func mergeInputs() (*Subtitle, *Subtitle) {
+	sinhala := &Subtitle{Cues: []Cue{
+		{Start: 1 * time.Second, End: 3 * time.Second, Lines: []string{"ආයුබෝවන්"}},
+		{Start: 4 * time.Second, End: 6 * time.Second, Lines: []string{"ස්තූතියි"}},
+	}}
+	english := &Subtitle{Cues: []Cue{
+		{Start: 1100 * time.Millisecond, End: 3050 * time.Millisecond, Lines: []string{"Hello"}},
+		{Start: 2900 * time.Millisecond, End: 5 * time.Second, Lines: []string{"Thank you"}},
+		{Start: 8 * time.Second, End: 9 * time.Second, Lines: []string{"[Music]"}, Position: AlignTop},
+	}}
+	return sinhala, english
+}
+
+func TestMerge(t *testing.T) {
+	sinhala, english := mergeInputs()
+	merged := Merge(sinhala, english)
+
+	want := []string{"ආයුබෝවන්|Hello", "ස්තූතියි|Thank you", "[Music]"}
+	if len(merged.Cues) != len(want) {
+		t.Fatalf("Merge() = %+v, want %d cues", merged.Cues, len(want))
+	}
+	for i, cue := range merged.Cues {
+		if got := strings.Join(cue.Lines, "|"); got != want[i] {
+			t.Errorf("Cue %d lines = %q, want %q", i, got, want[i])
+		}
+	}
+	if merged.Cues[0].Start != time.Second || merged.Cues[0].End != 3*time.Second || merged.Cues[2].Position != AlignDefault {
+		t.Errorf("Expected primary timings and default positions, got %+v", merged.Cues)
+	}
+	if len(sinhala.Cues[0].Lines) != 1 {
+		t.Errorf("Merge() changed its input: %+v", sinhala.Cues[0])
+	}
+}
+
+func TestStack(t *testing.T) {
+	sinhala, english := mergeInputs()
+	data, _ := Write(Stack(sinhala, english), FormatASS)
+
+	for _, want := range []string{
+		"Style: Primary,Arial,20,",
+		"Style: Secondary,Arial,16,",
+		"Dialogue: 0,0:00:01.00,0:00:03.00,Primary,,0,0,0,,ආයුබෝවන්\n",
+		"Dialogue: 0,0:00:01.10,0:00:03.05,Secondary,,0,0,0,,Hello\n",
+		"Dialogue: 0,0:00:08.00,0:00:09.00,Secondary,,0,0,0,,[Music]\n",
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("Stacked ASS = %q, want it to contain %q", data, want)
+		}
+	}
+	if !strings.Contains(string(data), ",8,10,10,10,1\n") {
+		t.Errorf("Expected the secondary style at the top, got %q", data)
+	}
+}
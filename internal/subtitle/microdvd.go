@@ -0,0 +1,145 @@
+package subtitle
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	microDVDLinePattern    = regexp.MustCompile(`^\{(\d+)\}\{(\d*)\}(.*)$`)
+	microDVDControlPattern = regexp.MustCompile(`\{([yY]):([^}]*)\}|\{[^}]*\}`)
+)
+
+func parseMicroDVD(text string) *Subtitle {
+	s := &Subtitle{FrameRate: DefaultFrameRate}
+	var open []int
+
+	for i, line := range strings.Split(text, "\n") {
+		m := microDVDLinePattern.FindStringSubmatch(strings.TrimSpace(line))
+		if m == nil {
+			continue
+		}
+
+		startFrame, _ := strconv.Atoi(m[1])
+		endFrame, _ := strconv.Atoi(m[2])
+
+		// The first line may state the frame rate as {1}{1}23.976
+		if i == 0 && startFrame == endFrame {
+			if fps, err := strconv.ParseFloat(strings.TrimSpace(m[3]), 64); err == nil && fps > 0 {
+				s.FrameRate = fps
+				continue
+			}
+		}
+
+		cue := Cue{Start: frameTime(startFrame, s.FrameRate), End: frameTime(endFrame, s.FrameRate)}
+		cue.Lines = parseMicroDVDText(m[3])
+		if m[2] == "" {
+			open = append(open, len(s.Cues))
+		}
+		s.Cues = append(s.Cues, cue)
+	}
+
+	// A cue without an end frame lasts until the next one, for at most defaultCueDuration
+	for _, i := range open {
+		cue := &s.Cues[i]
+		cue.End = cue.Start + defaultCueDuration
+		if i+1 < len(s.Cues) && s.Cues[i+1].Start > cue.Start {
+			cue.End = min(cue.End, s.Cues[i+1].Start)
+		}
+	}
+
+	return s
+}
+
+// parseMicroDVDText splits the text of a line at "|" and turns {y:i} style codes into
+// styling tags. Lowercase codes style one line, uppercase codes every line.
+func parseMicroDVDText(text string) []string {
+	var all string
+	lines := strings.Split(text, "|")
+	for i, line := range lines {
+		var tags string
+		line = microDVDControlPattern.ReplaceAllStringFunc(line, func(code string) string {
+			m := microDVDControlPattern.FindStringSubmatch(code)
+			styles := styleTags(m[2])
+			if m[1] == "Y" {
+				all += styles
+			} else if m[1] == "y" {
+				tags += styles
+			}
+			return ""
+		})
+
+		tags = all + tags
+		lines[i] = tags + line + closingTags(tags)
+	}
+	return lines
+}
+
+// styleTags returns the opening tags for the styles in a MicroDVD style code
+func styleTags(value string) string {
+	var tags string
+	for _, style := range "ibu" {
+		if strings.ContainsRune(strings.ToLower(value), style) {
+			tags += "<" + string(style) + ">"
+		}
+	}
+	return tags
+}
+
+func closingTags(tags string) string {
+	var closing string
+	for i := len(tags) - 3; i >= 0; i -= 3 {
+		closing += "</" + tags[i+1:i+3]
+	}
+	return closing
+}
+
+func writeMicroDVD(s *Subtitle) []byte {
+	fps := s.FrameRate
+	if fps <= 0 {
+		fps = DefaultFrameRate
+	}
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "{1}{1}%s\n", strconv.FormatFloat(fps, 'f', -1, 64))
+	for _, cue := range s.Cues {
+		lines := make([]string, len(cue.Lines))
+		for i, line := range cue.Lines {
+			lines[i] = microDVDLine(line)
+		}
+		fmt.Fprintf(&out, "{%d}{%d}%s\n", timeFrame(cue.Start, fps), timeFrame(cue.End, fps), strings.Join(lines, "|"))
+	}
+	return []byte(out.String())
+}
+
+// microDVDLine styles a whole line with a {y:...} code when it has styled text,
+// MicroDVD cannot style part of a line
+func microDVDLine(line string) string {
+	var styles string
+	for _, style := range "ibu" {
+		if strings.Contains(line, "<"+string(style)+">") {
+			styles += string(style)
+		}
+	}
+
+	line = styleTagPattern.ReplaceAllString(line, "")
+	if styles != "" {
+		return "{y:" + styles + "}" + line
+	}
+	return line
+}
+
+func frameTime(frame int, fps float64) time.Duration {
+	return time.Duration(math.Round(float64(frame) / fps * float64(time.Second)))
+}
+
+func timeFrame(d time.Duration, fps float64) int {
+	if d < 0 {
+		return 0
+	}
+	return int(math.Round(d.Seconds() * fps))
+}
@@ -0,0 +1,88 @@
+package subtitle
+
+import (
+	"ipmanlk/bettercopelk/internal/titleparser"
+	"strings"
+	"time"
+)
+
+// defaultCueDuration is given to cues that end before they start
+const defaultCueDuration = 2 * time.Second
+
+// Repair drops the lines containing any of the watermarks (ignoring case) and the cues
+// left empty or holding only a translator credit, sorts the cues, joins cues of the same style that start together, gives cues ending before they
+// start a short duration, and trims cues that overlap the next cue of the same style.
+// Writing the subtitle afterwards renumbers the cues and normalises line endings.
+func (s *Subtitle) Repair(watermarks []string) {
+	cues := s.Cues[:0]
+	for _, cue := range s.Cues {
+		lines := withoutWatermarks(cue.Lines, watermarks)
+		// "Translated by ... visit site.lk" is a credit cue even once the address is gone
+		if len(lines) < len(cue.Lines) && onlyCredits(lines) {
+			continue
+		}
+		cue.Lines = lines
+		if IsEmpty(cue) {
+			continue
+		}
+		if cue.End <= cue.Start {
+			cue.End = cue.Start + defaultCueDuration
+		}
+		cues = append(cues, cue)
+	}
+	sortCues(cues)
+
+	// last holds the index of the previous kept cue of each style
+	last := make(map[string]int)
+	repaired := cues[:0]
+	for _, cue := range cues {
+		i, ok := last[cue.Style]
+		if ok && repaired[i].Start == cue.Start {
+			repaired[i].Lines = append(repaired[i].Lines, cue.Lines...)
+			repaired[i].End = max(repaired[i].End, cue.End)
+			continue
+		}
+		if ok && repaired[i].End > cue.Start {
+			repaired[i].End = cue.Start
+		}
+
+		last[cue.Style] = len(repaired)
+		repaired = append(repaired, cue)
+	}
+
+	for i := range repaired {
+		repaired[i].Index = 0
+	}
+	s.Cues = repaired
+}
+
+// withoutWatermarks returns the lines that do not contain any of the watermarks
+func withoutWatermarks(lines []string, watermarks []string) []string {
+	kept := make([]string, 0, len(lines))
+	for _, line := range lines {
+		if !isWatermark(line, watermarks) {
+			kept = append(kept, line)
+		}
+	}
+	return kept
+}
+
+// onlyCredits reports whether every line is a translator credit
+func onlyCredits(lines []string) bool {
+	for _, line := range lines {
+		if !titleparser.IsCredit(styleTagPattern.ReplaceAllString(line, "")) {
+			return false
+		}
+	}
+	return true
+}
+
+func isWatermark(line string, watermarks []string) bool {
+	line = strings.ToLower(line)
+	for _, watermark := range watermarks {
+		if watermark != "" && strings.Contains(line, strings.ToLower(watermark)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,47 @@
+package subtitle
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSubtitle_Repair(t *testing.T) {
+	data := "1\r\n00:00:00,500 --> 00:00:02,000\r\nTranslated by Kasun\r\nvisit www.Baiscope.lk\r\n\r\n" +
+		"2\r\n00:00:02,000 --> 00:00:02,500\r\n<i>BAISCOPE.LK</i>\r\n\r\n" +
+		"3\r\n00:00:02,500 --> 00:00:03,000\r\n<i>Subtitled by Nimal</i>\r\n<i>www.baiscope.lk</i>\r\n\r\n" +
+		"7\r\n00:00:03,000 --> 00:00:05,000\r\nFirst\r\n\r\n" +
+		"8\r\n00:00:04,000 --> 00:00:06,000\r\nSecond\r\n\r\n" +
+		"9\r\n00:00:07,000 --> 00:00:08,000\r\n<i></i>\r\n\r\n" +
+		"10\r\n00:00:04,000 --> 00:00:04,500\r\nSame start\r\n\r\n" +
+		"11\r\n00:00:10,000 --> 00:00:09,000\r\nBackwards\r\n"
+
+	s, _ := Parse(FormatSRT, []byte(data))
+	s.Repair([]string{"baiscope.lk"})
+
+	if issues := Validate(s); len(issues) != 0 {
+		t.Errorf("Validate() after Repair() = %+v", issues)
+	}
+
+	want := []struct {
+		start, end time.Duration
+		text       string
+	}{
+		{3 * time.Second, 4 * time.Second, "First"},
+		{4 * time.Second, 6 * time.Second, "Second|Same start"},
+		{10 * time.Second, 12 * time.Second, "Backwards"},
+	}
+	if len(s.Cues) != len(want) {
+		t.Fatalf("Repair() = %+v, want %d cues", s.Cues, len(want))
+	}
+	for i, cue := range s.Cues {
+		if cue.Start != want[i].start || cue.End != want[i].end || strings.Join(cue.Lines, "|") != want[i].text {
+			t.Errorf("Cue %d = %+v, want %+v", i, cue, want[i])
+		}
+	}
+
+	out, _ := Write(s, FormatSRT)
+	if strings.Contains(string(out), "\r") || !strings.HasPrefix(string(out), "1\n00:00:03,000 --> 00:00:04,000\nFirst\n\n2\n") {
+		t.Errorf("Write() after Repair() = %q", out)
+	}
+}
@@ -0,0 +1,53 @@
+package subtitle
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var srtPositionPattern = regexp.MustCompile(`^\{\\an([1-9])\}`)
+
+func parseSRT(text string) *Subtitle {
+	s := &Subtitle{}
+
+	for _, block := range splitBlocks(text) {
+		for i, line := range block {
+			start, end, _, ok := parseTiming(line)
+			if !ok {
+				continue
+			}
+
+			cue := Cue{Start: start, End: end}
+			if i > 0 {
+				cue.Index, _ = strconv.Atoi(strings.TrimSpace(block[i-1]))
+			}
+			for _, text := range block[i+1:] {
+				if m := srtPositionPattern.FindStringSubmatch(text); m != nil {
+					n, _ := strconv.Atoi(m[1])
+					cue.Position = Alignment(n)
+					text = text[len(m[0]):]
+				}
+				cue.Lines = append(cue.Lines, text)
+			}
+			s.Cues = append(s.Cues, cue)
+			break
+		}
+	}
+
+	return s
+}
+
+func writeSRT(s *Subtitle) []byte {
+	var out strings.Builder
+	for i, cue := range s.Cues {
+		fmt.Fprintf(&out, "%d\n%s --> %s\n", i+1, formatTimestamp(cue.Start, ","), formatTimestamp(cue.End, ","))
+		if cue.Position != AlignDefault && cue.Position != AlignBottom {
+			fmt.Fprintf(&out, "{\\an%d}", cue.Position)
+		}
+		out.WriteString(strings.Join(cue.Lines, "\n"))
+		out.WriteString("\n\n")
+	}
+	return []byte(out.String())
+}
@@ -0,0 +1,189 @@
+package subtitle
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultFrameRate is used for MicroDVD files that do not state their frame rate
+const DefaultFrameRate = 23.976
+
+// ErrUnknownFormat is returned for formats that cannot be parsed or written
+var ErrUnknownFormat = errors.New("unknown subtitle format")
+
+// Alignment places a cue on screen using the numeric keypad layout of ASS: 1-3 along the
+// bottom, 4-6 in the middle and 7-9 at the top, from left to right
+type Alignment int
+
+const (
+	AlignDefault Alignment = 0
+	AlignBottom  Alignment = 2
+	AlignMiddle  Alignment = 5
+	AlignTop     Alignment = 8
+)
+
+// Subtitle is a subtitle file independent of its format
+type Subtitle struct {
+	Cues []Cue
+	// Styles holds the ASS/SSA styles, in ASS terms
+	Styles []Style
+	// FrameRate converts the frames of MicroDVD files to times
+	FrameRate float64
+}
+
+// Cue is a piece of text shown between two times
+type Cue struct {
+	// Index is the number a SRT cue has in the file, 0 for other formats
+	Index int
+	Start time.Duration
+	End   time.Duration
+	// Lines holds the text, styled with <i>, <b> and <u> tags
+	Lines    []string
+	Style    string
+	Position Alignment
+}
+
+// Style is an ASS style. Fields holds the values by their V4+ Styles field name.
+type Style struct {
+	Name   string
+	Fields map[string]string
+}
+
+var (
+	timestampPattern = regexp.MustCompile(`^(?:(\d+):)?(\d{1,2}):(\d{1,2})(?:[,.:](\d+))?$`)
+	styleTagPattern  = regexp.MustCompile(`</?([a-zA-Z]+)[^>]*>`)
+)
+
+// Parse reads a UTF-8 subtitle file in the given format. Malformed cues are skipped.
+func Parse(format string, data []byte) (*Subtitle, error) {
+	text := strings.TrimPrefix(string(data), "\uFEFF")
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\r", "\n")
+
+	switch format {
+	case FormatSRT:
+		return parseSRT(text), nil
+	case FormatVTT:
+		return parseVTT(text), nil
+	case FormatASS, FormatSSA:
+		return parseASS(text), nil
+	case FormatMicroDVD:
+		return parseMicroDVD(text), nil
+	default:
+		return nil, fmt.Errorf("%q: %w", format, ErrUnknownFormat)
+	}
+}
+
+// Write returns s as a UTF-8 file in the given format
+func Write(s *Subtitle, format string) ([]byte, error) {
+	switch format {
+	case FormatSRT:
+		return writeSRT(s), nil
+	case FormatVTT:
+		return writeVTT(s), nil
+	case FormatASS:
+		return writeASS(s, false), nil
+	case FormatSSA:
+		return writeASS(s, true), nil
+	case FormatMicroDVD:
+		return writeMicroDVD(s), nil
+	default:
+		return nil, fmt.Errorf("%q: %w", format, ErrUnknownFormat)
+	}
+}
+
+// Extension returns the file extension used for a format
+func Extension(format string) string {
+	if format == FormatMicroDVD {
+		return ".sub"
+	}
+	return "." + format
+}
+
+// ParseTimestamp reads "h:mm:ss,fff" style times. Hours are optional, the fraction may
+// be separated by a comma, a dot or a colon and have any number of digits.
+func ParseTimestamp(s string) (time.Duration, bool) {
+	m := timestampPattern.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return 0, false
+	}
+
+	hours, _ := strconv.Atoi(m[1])
+	minutes, _ := strconv.Atoi(m[2])
+	seconds, _ := strconv.Atoi(m[3])
+	d := time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute + time.Duration(seconds)*time.Second
+
+	if fraction := m[4]; fraction != "" {
+		if len(fraction) > 9 {
+			fraction = fraction[:9]
+		}
+		n, _ := strconv.Atoi(fraction + strings.Repeat("0", 9-len(fraction)))
+		d += time.Duration(n)
+	}
+
+	return d, true
+}
+
+// formatTimestamp writes d as hh:mm:ss followed by sep and the milliseconds
+func formatTimestamp(d time.Duration, sep string) string {
+	if d < 0 {
+		d = 0
+	}
+	ms := d.Round(time.Millisecond).Milliseconds()
+	return fmt.Sprintf("%02d:%02d:%02d%s%03d", ms/3600000, ms/60000%60, ms/1000%60, sep, ms%1000)
+}
+
+// stripTags removes every tag other than the <i>, <b> and <u> styling tags
+func stripTags(text string) string {
+	return styleTagPattern.ReplaceAllStringFunc(text, func(tag string) string {
+		name := strings.ToLower(styleTagPattern.FindStringSubmatch(tag)[1])
+		if name != "i" && name != "b" && name != "u" {
+			return ""
+		}
+		if strings.HasPrefix(tag, "</") {
+			return "</" + name + ">"
+		}
+		return "<" + name + ">"
+	})
+}
+
+// splitBlocks splits text into its blocks of lines separated by blank lines
+func splitBlocks(text string) [][]string {
+	var blocks [][]string
+	var block []string
+	for _, line := range strings.Split(text, "\n") {
+		if strings.TrimSpace(line) == "" {
+			if len(block) > 0 {
+				blocks = append(blocks, block)
+				block = nil
+			}
+			continue
+		}
+		block = append(block, strings.TrimRight(line, " \t"))
+	}
+	if len(block) > 0 {
+		blocks = append(blocks, block)
+	}
+	return blocks
+}
+
+// parseTiming reads a "start --> end" line, returning what follows the end time
+func parseTiming(line string) (start, end time.Duration, settings string, ok bool) {
+	from, to, found := strings.Cut(line, "-->")
+	if !found {
+		return 0, 0, "", false
+	}
+
+	fields := strings.Fields(to)
+	if len(fields) == 0 {
+		return 0, 0, "", false
+	}
+
+	start, startOK := ParseTimestamp(from)
+	end, endOK := ParseTimestamp(fields[0])
+	return start, end, strings.Join(fields[1:], " "), startOK && endOK
+}
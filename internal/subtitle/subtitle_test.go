@@ -0,0 +1,144 @@
+package subtitle
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		data   string
+		want   Cue
+	}{
+		{"srt", FormatSRT, "\uFEFF1\r\n00:00:01,500 --> 00:00:02,250\r\n{\\an8}<i>Hi</i>\r\nthere\r\n\r\n", Cue{Index: 1, Start: 1500 * time.Millisecond, End: 2250 * time.Millisecond, Lines: []string{"<i>Hi</i>", "there"}, Position: AlignTop}},
+		{"vtt", FormatVTT, "WEBVTT\n\nNOTE a comment\n\nintro\n00:01.500 --> 00:02.250 line:0 align:start\n<v Bob><i>Hi</i> &amp; <c.red>there</c>\n", Cue{Start: 1500 * time.Millisecond, End: 2250 * time.Millisecond, Lines: []string{"<i>Hi</i> & there"}, Position: 7}},
+		{"ass", FormatASS, "[Script Info]\n\n[V4+ Styles]\nFormat: Name, Fontname, Fontsize, PrimaryColour, SecondaryColour, OutlineColour, BackColour, Bold, Italic, Underline, StrikeOut, ScaleX, ScaleY, Spacing, Angle, BorderStyle, Outline, Shadow, Alignment, MarginL, MarginR, MarginV, Encoding\nStyle: Sign,Arial,20,&H00FFFFFF,&H000000FF,&H00000000,&H00000000,0,0,0,0,100,100,0,0,1,2,2,8,10,10,10,1\n\n[Events]\nFormat: Layer, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text\nDialogue: 0,0:00:01.50,0:00:02.25,Sign,,0,0,0,,{\\an8\\i1}Hi, you{\\i0}\\Nthere\n", Cue{Start: 1500 * time.Millisecond, End: 2250 * time.Millisecond, Lines: []string{"<i>Hi, you</i>", "there"}, Style: "Sign", Position: AlignTop}},
+		{"ssa", FormatSSA, "[Script Info]\n\n[V4 Styles]\n\n[Events]\nFormat: Marked, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text\nDialogue: Marked=0,0:00:01.50,0:00:02.25,*Default,,0,0,0,,{\\a6}Hi\n", Cue{Start: 1500 * time.Millisecond, End: 2250 * time.Millisecond, Lines: []string{"Hi"}, Style: "Default", Position: AlignTop}},
+		{"microdvd", FormatMicroDVD, "{1}{1}25\n{25}{50}{y:i}Hi|there\n", Cue{Start: time.Second, End: 2 * time.Second, Lines: []string{"<i>Hi</i>", "there"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := Parse(tt.format, []byte(tt.data))
+			if err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
+			if len(s.Cues) != 1 {
+				t.Fatalf("Expected 1 cue, got %+v", s.Cues)
+			}
+			got := s.Cues[0]
+			if got.Index != tt.want.Index || got.Start != tt.want.Start || got.End != tt.want.End || got.Style != tt.want.Style ||
+				got.Position != tt.want.Position || strings.Join(got.Lines, "|") != strings.Join(tt.want.Lines, "|") {
+				t.Errorf("Parse() cue = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParse_MicroDVDWithoutEndFrame(t *testing.T) {
+	s, err := Parse(FormatMicroDVD, []byte("{1}{1}25\n{25}{}Hi\n{50}{}there\n{200}{250}Bye\n"))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	want := [][2]time.Duration{
+		{time.Second, 2 * time.Second},
+		{2 * time.Second, 4 * time.Second},
+		{8 * time.Second, 10 * time.Second},
+	}
+	if len(s.Cues) != len(want) {
+		t.Fatalf("Expected %d cues, got %+v", len(want), s.Cues)
+	}
+	for i, cue := range s.Cues {
+		if cue.Start != want[i][0] || cue.End != want[i][1] {
+			t.Errorf("Cue %d = %v --> %v, want %v --> %v", i+1, cue.Start, cue.End, want[i][0], want[i][1])
+		}
+	}
+}
+
+func TestWrite(t *testing.T) {
+	s := &Subtitle{
+		FrameRate: 25,
+		Cues: []Cue{
+			{Start: 1500 * time.Millisecond, End: 2250 * time.Millisecond, Lines: []string{"<i>Hi</i> & you", "there"}},
+			{Start: time.Hour + 2*time.Second, End: time.Hour + 3*time.Second, Lines: []string{"Top"}, Position: AlignTop},
+		},
+	}
+
+	tests := []struct {
+		format string
+		want   []string
+	}{
+		{FormatSRT, []string{"1\n00:00:01,500 --> 00:00:02,250\n<i>Hi</i> & you\nthere\n\n", "2\n01:00:02,000 --> 01:00:03,000\n{\\an8}Top\n"}},
+		{FormatVTT, []string{"WEBVTT\n\n00:00:01.500 --> 00:00:02.250\n<i>Hi</i> &amp; you\nthere\n\n", "01:00:02.000 --> 01:00:03.000 line:0\nTop\n"}},
+		{FormatASS, []string{"[V4+ Styles]", "Style: Default,Arial,20,&H00FFFFFF", "Dialogue: 0,0:00:01.50,0:00:02.25,Default,,0,0,0,,{\\i1}Hi{\\i0} & you\\Nthere\n", "Dialogue: 0,1:00:02.00,1:00:03.00,Default,,0,0,0,,{\\an8}Top\n"}},
+		{FormatSSA, []string{"[V4 Styles]", "Style: Default,Arial,20,16777215,255,0,0,", "Dialogue: Marked=0,1:00:02.00,1:00:03.00,Default,,0,0,0,,{\\a6}Top\n"}},
+		{FormatMicroDVD, []string{"{1}{1}25\n{38}{56}{y:i}Hi & you|there\n{90050}{90075}Top\n"}},
+	}
+
+	for _, tt := range tests {
+		data, err := Write(s, tt.format)
+		if err != nil {
+			t.Fatalf("Write(%s) failed: %v", tt.format, err)
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(string(data), want) {
+				t.Errorf("Write(%s) = %q, want it to contain %q", tt.format, data, want)
+			}
+		}
+	}
+
+	if _, err := Write(s, "txt"); err == nil {
+		t.Error("Expected an error for an unknown format")
+	}
+}
+
+func TestWrite_VTTEscapesText(t *testing.T) {
+	s := &Subtitle{Cues: []Cue{
+		{Start: time.Second, End: 2 * time.Second, Lines: []string{"<i>1 < 2</i> & <font color=red>3 > 2</font>"}},
+	}}
+
+	data, err := Write(s, FormatVTT)
+	if err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if want := "\n<i>1 &lt; 2</i> &amp; 3 &gt; 2\n"; !strings.Contains(string(data), want) {
+		t.Errorf("Write(vtt) = %q, want it to contain %q", data, want)
+	}
+
+	parsed, err := Parse(FormatVTT, data)
+	if err != nil || len(parsed.Cues) != 1 || parsed.Cues[0].Lines[0] != "<i>1 < 2</i> & 3 > 2" {
+		t.Errorf("Parse() of the written file = %+v, %v, want the text back", parsed, err)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	original := &Subtitle{
+		FrameRate: DefaultFrameRate,
+		Cues: []Cue{
+			{Start: time.Second, End: 2 * time.Second, Lines: []string{"<i>ආයුබෝවන්</i>", "Hello"}},
+			{Start: 3 * time.Second, End: 4 * time.Second, Lines: []string{"Top"}, Position: AlignTop},
+		},
+	}
+
+	for _, format := range []string{FormatSRT, FormatVTT, FormatASS, FormatSSA} {
+		data, _ := Write(original, format)
+		if detected := DetectFormat("", data); detected != format {
+			t.Errorf("DetectFormat() of written %s = %q", format, detected)
+		}
+
+		parsed, err := Parse(format, data)
+		if err != nil || len(parsed.Cues) != 2 {
+			t.Fatalf("Parse(%s) = %+v, %v", format, parsed, err)
+		}
+		for i, cue := range parsed.Cues {
+			want := original.Cues[i]
+			if cue.Start != want.Start || cue.End != want.End || cue.Position != want.Position || strings.Join(cue.Lines, "|") != strings.Join(want.Lines, "|") {
+				t.Errorf("%s round trip cue %d = %+v, want %+v", format, i, cue, want)
+			}
+		}
+	}
+}
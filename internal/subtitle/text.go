@@ -0,0 +1,114 @@
+package subtitle
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+)
+
+// tagPattern matches the formatting tags that can appear inside cue text: the <i>, <b>,
+// <u> and <font> tags and ASS override blocks. Other brackets are kept as text, legacy
+// Sinhala fonts use "<", ">" and "{" for letters.
+var tagPattern = regexp.MustCompile(`(?i)</?(?:[ibu]|font)(?:\s[^>]*)?>|\{\\[^}]*\}`)
+
+// MapText returns a copy of a UTF-8 subtitle file with fn applied to the text of every
+// cue. Numbering, timings, headers and formatting tags are left untouched.
+func MapText(format string, data []byte, fn func(string) string) []byte {
+	lines := strings.SplitAfter(string(data), "\n")
+	inHeader := format == FormatVTT
+
+	var out strings.Builder
+	out.Grow(len(data))
+
+	for _, line := range lines {
+		content := strings.TrimRight(line, "\r\n")
+		ending := line[len(content):]
+		trimmed := strings.TrimSpace(content)
+
+		switch format {
+		case FormatSRT, FormatVTT:
+			switch {
+			case trimmed == "":
+				inHeader = false
+			case inHeader, isNumber(trimmed), srtTimingPattern.MatchString(trimmed), vttTimingPattern.MatchString(trimmed):
+			default:
+				content = mapSegments(content, fn)
+			}
+		case FormatASS, FormatSSA:
+			if strings.HasPrefix(trimmed, "Dialogue:") {
+				content = mapDialogue(content, fn)
+			}
+		case FormatMicroDVD:
+			if prefix := microDVDPattern.FindString(trimmed); prefix != "" {
+				// "|" is a line break in MicroDVD
+				parts := strings.Split(trimmed[len(prefix):], "|")
+				for i, part := range parts {
+					parts[i] = mapSegments(part, fn)
+				}
+				content = prefix + strings.Join(parts, "|")
+			}
+		}
+
+		out.WriteString(content)
+		out.WriteString(ending)
+	}
+
+	return []byte(out.String())
+}
+
+// Text returns the text of every cue of a UTF-8 subtitle file, one line per cue line
+func Text(format string, data []byte) string {
+	var text bytes.Buffer
+	MapText(format, data, func(s string) string {
+		text.WriteString(s)
+		text.WriteByte('\n')
+		return s
+	})
+	return text.String()
+}
+
+// mapDialogue applies fn to the text field of an ASS/SSA dialogue line, the last of its
+// ten comma separated fields
+func mapDialogue(line string, fn func(string) string) string {
+	offset := 0
+	for range 9 {
+		i := strings.IndexByte(line[offset:], ',')
+		if i < 0 {
+			return line
+		}
+		offset += i + 1
+	}
+	// "\N" is a line break inside the text field
+	text := strings.ReplaceAll(line[offset:], `\N`, "\n")
+	parts := strings.Split(text, "\n")
+	for i, part := range parts {
+		parts[i] = mapSegments(part, fn)
+	}
+	return line[:offset] + strings.Join(parts, `\N`)
+}
+
+// mapSegments applies fn to the parts of text between formatting tags
+func mapSegments(text string, fn func(string) string) string {
+	var out strings.Builder
+	last := 0
+	for _, loc := range tagPattern.FindAllStringIndex(text, -1) {
+		if loc[0] > last {
+			out.WriteString(fn(text[last:loc[0]]))
+		}
+		out.WriteString(text[loc[0]:loc[1]])
+		last = loc[1]
+	}
+	if last < len(text) {
+		out.WriteString(fn(text[last:]))
+	}
+	return out.String()
+}
+
+func isNumber(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
@@ -0,0 +1,45 @@
+package subtitle
+
+import (
+	"ipmanlk/bettercopelk/internal/sinhala"
+	"strings"
+	"testing"
+)
+
+func TestMapText(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		data   string
+		want   string
+	}{
+		{"srt", FormatSRT, "1\r\n00:00:01,000 --> 00:00:02,000\r\n<i>hi</i> there\r\n\r\n", "1\r\n00:00:01,000 --> 00:00:02,000\r\n<i>HI</i> THERE\r\n\r\n"},
+		{"vtt header", FormatVTT, "WEBVTT\nKind: captions\n\n00:01.000 --> 00:02.000\nhi\n", "WEBVTT\nKind: captions\n\n00:01.000 --> 00:02.000\nHI\n"},
+		{"ass", FormatASS, "[Events]\nFormat: Layer, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text\nDialogue: 0,0:00:01.00,0:00:02.00,Default,,0,0,0,,{\\i1}hi, you\\Nthere\n", "[Events]\nFormat: Layer, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text\nDialogue: 0,0:00:01.00,0:00:02.00,Default,,0,0,0,,{\\i1}HI, YOU\\NTHERE\n"},
+		{"microdvd", FormatMicroDVD, "{24}{48}hi|there\n", "{24}{48}HI|THERE\n"},
+		{"brackets are text", FormatSRT, "1\n00:00:01,000 --> 00:00:02,000\n<b>a <x> {y}</b>\n", "1\n00:00:01,000 --> 00:00:02,000\n<b>A <X> {Y}</b>\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(MapText(tt.format, []byte(tt.data), strings.ToUpper)); got != tt.want {
+				t.Errorf("MapText() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMapText_LegacySinhala(t *testing.T) {
+	data := "1\n00:00:01,000 --> 00:00:02,000\n<i>fld< mdg> ,sh{</i>\n"
+	want := "1\n00:00:01,000 --> 00:00:02,000\n<i>කොළ පාටඝ ලියඥ</i>\n"
+	if got := string(MapText(FormatSRT, []byte(data), sinhala.ToUnicode)); got != want {
+		t.Errorf("MapText() = %q, want %q", got, want)
+	}
+}
+
+func TestText(t *testing.T) {
+	data := "1\n00:00:01,000 --> 00:00:02,000\nfirst\nline\n\n2\n00:00:03,000 --> 00:00:04,000\nsecond\n"
+	if got := Text(FormatSRT, []byte(data)); got != "first\nline\nsecond\n" {
+		t.Errorf("Text() = %q", got)
+	}
+}
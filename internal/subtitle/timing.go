@@ -0,0 +1,65 @@
+package subtitle
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+// ErrInvalidTiming is returned for frame rates and sync points that cannot retime a subtitle
+var ErrInvalidTiming = errors.New("invalid timing adjustment")
+
+// Shift moves every cue by offset. Cues moved entirely before the start of the video are
+// dropped, cues moved partly before it start at zero.
+func (s *Subtitle) Shift(offset time.Duration) {
+	s.retime(func(t time.Duration) time.Duration {
+		return t + offset
+	})
+}
+
+// ChangeFrameRate retimes a subtitle made for a release at the from frame rate to a
+// release of the same video at the to frame rate, such as 25 to 23.976 for PAL releases
+func (s *Subtitle) ChangeFrameRate(from, to float64) error {
+	if from <= 0 || to <= 0 {
+		return ErrInvalidTiming
+	}
+
+	ratio := from / to
+	s.retime(func(t time.Duration) time.Duration {
+		return time.Duration(math.Round(float64(t) * ratio))
+	})
+	return nil
+}
+
+// Sync retimes cues linearly so that time from1 moves to to1 and from2 moves to to2,
+// which fixes both an offset and a frame rate difference from two known lines
+func (s *Subtitle) Sync(from1, to1, from2, to2 time.Duration) error {
+	if from1 == from2 {
+		return ErrInvalidTiming
+	}
+
+	scale := float64(to2-to1) / float64(from2-from1)
+	if scale <= 0 {
+		return ErrInvalidTiming
+	}
+
+	s.retime(func(t time.Duration) time.Duration {
+		return to1 + time.Duration(math.Round(float64(t-from1)*scale))
+	})
+	return nil
+}
+
+// retime maps the times of every cue through fn, dropping the cues that end up before
+// the start of the video and trimming those that straddle it
+func (s *Subtitle) retime(fn func(time.Duration) time.Duration) {
+	cues := s.Cues[:0]
+	for _, cue := range s.Cues {
+		cue.Start, cue.End = fn(cue.Start), fn(cue.End)
+		if cue.End <= 0 {
+			continue
+		}
+		cue.Start = max(cue.Start, 0)
+		cues = append(cues, cue)
+	}
+	s.Cues = cues
+}
@@ -0,0 +1,64 @@
+package subtitle
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func newTimedSubtitle() *Subtitle {
+	return &Subtitle{Cues: []Cue{
+		{Start: time.Second, End: 2 * time.Second},
+		{Start: 100 * time.Second, End: 102 * time.Second},
+	}}
+}
+
+func TestSubtitle_Shift(t *testing.T) {
+	s := newTimedSubtitle()
+	s.Shift(-1500 * time.Millisecond)
+
+	if s.Cues[0].Start != 0 || s.Cues[0].End != 500*time.Millisecond || s.Cues[1].Start != 98500*time.Millisecond {
+		t.Errorf("Shift() = %+v", s.Cues)
+	}
+}
+
+func TestSubtitle_ShiftDropsCuesBeforeStart(t *testing.T) {
+	s := newTimedSubtitle()
+	s.Shift(-2 * time.Second)
+
+	if len(s.Cues) != 1 || s.Cues[0].Start != 98*time.Second {
+		t.Errorf("Expected the cue ending at the start of the video to be dropped, got %+v", s.Cues)
+	}
+}
+
+func TestSubtitle_ChangeFrameRate(t *testing.T) {
+	s := newTimedSubtitle()
+	if err := s.ChangeFrameRate(25, 23.976); err != nil {
+		t.Fatalf("ChangeFrameRate failed: %v", err)
+	}
+
+	// 100s at 25 fps is frame 2500, which plays at 104.271s at 23.976 fps
+	if got := s.Cues[1].Start.Round(time.Millisecond); got != 104271*time.Millisecond {
+		t.Errorf("ChangeFrameRate() start = %v, want 1m44.271s", got)
+	}
+
+	if err := s.ChangeFrameRate(0, 25); !errors.Is(err, ErrInvalidTiming) {
+		t.Errorf("Expected ErrInvalidTiming for a zero frame rate, got %v", err)
+	}
+}
+
+func TestSubtitle_Sync(t *testing.T) {
+	s := newTimedSubtitle()
+
+	// The first line should be at 2s and the last at 201s: a 1s offset and double speed
+	if err := s.Sync(time.Second, 2*time.Second, 100*time.Second, 200*time.Second); err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	if s.Cues[0].Start != 2*time.Second || s.Cues[0].End != 4*time.Second || s.Cues[1].End != 204*time.Second {
+		t.Errorf("Sync() = %+v", s.Cues)
+	}
+
+	if err := s.Sync(time.Second, time.Second, time.Second, 2*time.Second); !errors.Is(err, ErrInvalidTiming) {
+		t.Errorf("Expected ErrInvalidTiming for equal sync points, got %v", err)
+	}
+}
@@ -0,0 +1,124 @@
+package subtitle
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Kinds of issues found by Validate
+const (
+	IssueEmpty     = "empty"
+	IssueDuration  = "duration"
+	IssueOrder     = "order"
+	IssueOverlap   = "overlap"
+	IssueNumbering = "numbering"
+)
+
+// Kinds of issues with a whole file, reported along with the ones found by Validate
+const (
+	IssueNoCues     = "no_cues"
+	IssueEncoding   = "encoding"
+	IssueLegacyFont = "legacy_font"
+)
+
+// Issue is a problem with a cue. Cue is the position of the cue in the file, from 1.
+type Issue struct {
+	Cue     int
+	Kind    string
+	Message string
+}
+
+// Validate checks the cues of s for empty text, durations that are not positive, cues
+// out of order or overlapping the previous cue of the same style, and SRT numbers that
+// do not follow on from the previous cue
+func Validate(s *Subtitle) []Issue {
+	var issues []Issue
+	add := func(i int, kind, format string, args ...any) {
+		issues = append(issues, Issue{Cue: i + 1, Kind: kind, Message: fmt.Sprintf(format, args...)})
+	}
+
+	previous := make(map[string]Cue)
+	for i, cue := range s.Cues {
+		if IsEmpty(cue) {
+			add(i, IssueEmpty, "cue has no text")
+		}
+		if cue.End <= cue.Start {
+			add(i, IssueDuration, "cue ends at %s, not after it starts at %s", FormatTimestamp(cue.End), FormatTimestamp(cue.Start))
+		}
+
+		if i > 0 && cue.Start < s.Cues[i-1].Start {
+			add(i, IssueOrder, "cue starts at %s, before the previous cue", FormatTimestamp(cue.Start))
+		} else if last, ok := previous[cue.Style]; ok && cue.Start < last.End {
+			add(i, IssueOverlap, "cue starts at %s, before the previous cue ends at %s", FormatTimestamp(cue.Start), FormatTimestamp(last.End))
+		}
+		previous[cue.Style] = cue
+
+		if cue.Index != 0 {
+			want := 1
+			if i > 0 {
+				want = s.Cues[i-1].Index + 1
+			}
+			if cue.Index != want {
+				add(i, IssueNumbering, "cue is numbered %d, expected %d", cue.Index, want)
+			}
+		}
+	}
+
+	return issues
+}
+
+// IsEmpty reports whether a cue has no text other than styling tags and spaces
+func IsEmpty(cue Cue) bool {
+	for _, line := range cue.Lines {
+		if strings.TrimSpace(styleTagPattern.ReplaceAllString(line, "")) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// Span returns the start of the first cue and the end of the last cue to end
+func (s *Subtitle) Span() (time.Duration, time.Duration) {
+	if len(s.Cues) == 0 {
+		return 0, 0
+	}
+
+	start, end := s.Cues[0].Start, s.Cues[0].End
+	for _, cue := range s.Cues[1:] {
+		start = min(start, cue.Start)
+		end = max(end, cue.End)
+	}
+	return start, end
+}
+
+// SinhalaPercent returns the share of the letters in the cue text that are Sinhala,
+// from 0 to 100 with one decimal
+func (s *Subtitle) SinhalaPercent() float64 {
+	var letters, sinhala int
+	for _, cue := range s.Cues {
+		for _, line := range cue.Lines {
+			for _, r := range styleTagPattern.ReplaceAllString(line, "") {
+				switch {
+				case r >= 0x0D80 && r <= 0x0DFF:
+					sinhala++
+					letters++
+				case unicode.IsLetter(r):
+					letters++
+				}
+			}
+		}
+	}
+
+	if letters == 0 {
+		return 0
+	}
+	return math.Round(float64(sinhala)/float64(letters)*1000) / 10
+}
+
+// FormatTimestamp writes d as hh:mm:ss.mmm
+func FormatTimestamp(d time.Duration) string {
+	return formatTimestamp(d, ".")
+}
@@ -0,0 +1,63 @@
+package subtitle
+
+import (
+	"testing"
+	"time"
+)
+
+func TestValidate(t *testing.T) {
+	data := "1\n00:00:01,000 --> 00:00:03,000\nආයුබෝවන්\n\n" +
+		"2\n00:00:02,500 --> 00:00:04,000\nOverlap\n\n" +
+		"4\n00:00:05,000 --> 00:00:04,000\nBackwards\n\n" +
+		"5\n00:00:06,000 --> 00:00:07,000\n<i> </i>\n\n" +
+		"6\n00:00:05,500 --> 00:00:08,000\nOut of order\n"
+
+	s, _ := Parse(FormatSRT, []byte(data))
+	issues := Validate(s)
+
+	want := []struct {
+		cue  int
+		kind string
+	}{
+		{2, IssueOverlap},
+		{3, IssueDuration},
+		{3, IssueNumbering},
+		{4, IssueEmpty},
+		{5, IssueOrder},
+	}
+	if len(issues) != len(want) {
+		t.Fatalf("Validate() = %+v, want %d issues", issues, len(want))
+	}
+	for i, issue := range issues {
+		if issue.Cue != want[i].cue || issue.Kind != want[i].kind || issue.Message == "" {
+			t.Errorf("Issue %d = %+v, want %s on cue %d", i, issue, want[i].kind, want[i].cue)
+		}
+	}
+
+	start, end := s.Span()
+	if start != time.Second || end != 8*time.Second {
+		t.Errorf("Span() = %v, %v, want 1s, 8s", start, end)
+	}
+}
+
+func TestValidate_StylesMayOverlap(t *testing.T) {
+	s := &Subtitle{Cues: []Cue{
+		{Start: time.Second, End: 3 * time.Second, Lines: []string{"a"}, Style: PrimaryStyle},
+		{Start: 2 * time.Second, End: 4 * time.Second, Lines: []string{"b"}, Style: SecondaryStyle},
+	}}
+	if issues := Validate(s); len(issues) != 0 {
+		t.Errorf("Validate() = %+v, want no issues for cues in different styles", issues)
+	}
+}
+
+func TestSubtitle_SinhalaPercent(t *testing.T) {
+	s := &Subtitle{Cues: []Cue{{Lines: []string{"<i>අම්මා</i>", "mama"}}}}
+
+	// අම්මා is 5 runes, all Sinhala, against 4 Latin letters
+	if got := s.SinhalaPercent(); got != 55.6 {
+		t.Errorf("SinhalaPercent() = %v, want 55.6", got)
+	}
+	if got := (&Subtitle{}).SinhalaPercent(); got != 0 {
+		t.Errorf("SinhalaPercent() of an empty subtitle = %v, want 0", got)
+	}
+}
@@ -0,0 +1,127 @@
+package subtitle
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var (
+	vttUnescaper = strings.NewReplacer("&amp;", "&", "&lt;", "<", "&gt;", ">", "&nbsp;", "\u00A0", "&lrm;", "\u200E", "&rlm;", "\u200F")
+	vttEscaper   = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+)
+
+func parseVTT(text string) *Subtitle {
+	s := &Subtitle{}
+
+	for i, block := range splitBlocks(text) {
+		if i == 0 && strings.HasPrefix(block[0], "WEBVTT") {
+			continue
+		}
+		if first := strings.Fields(block[0]); len(first) > 0 && (first[0] == "NOTE" || first[0] == "STYLE" || first[0] == "REGION") {
+			continue
+		}
+
+		for j, line := range block {
+			start, end, settings, ok := parseTiming(line)
+			if !ok {
+				continue
+			}
+
+			cue := Cue{Start: start, End: end, Position: vttPosition(settings)}
+			for _, text := range block[j+1:] {
+				cue.Lines = append(cue.Lines, vttUnescaper.Replace(stripTags(text)))
+			}
+			s.Cues = append(s.Cues, cue)
+			break
+		}
+	}
+
+	return s
+}
+
+// vttPosition maps the line and align cue settings to an alignment
+func vttPosition(settings string) Alignment {
+	row, column := 0, 2
+	for _, setting := range strings.Fields(settings) {
+		name, value, _ := strings.Cut(setting, ":")
+		switch name {
+		case "line":
+			value, _, _ = strings.Cut(value, ",")
+			if percent, isPercent := strings.CutSuffix(value, "%"); isPercent {
+				n, _ := strconv.ParseFloat(percent, 64)
+				switch {
+				case n < 33:
+					row = 6
+				case n < 66:
+					row = 3
+				}
+			} else if n, err := strconv.Atoi(value); err == nil && n >= 0 {
+				row = 6
+			}
+		case "align":
+			switch value {
+			case "start", "left":
+				column = 1
+			case "end", "right":
+				column = 3
+			}
+		}
+	}
+
+	if row == 0 && column == 2 {
+		return AlignDefault
+	}
+	return Alignment(row + column)
+}
+
+func writeVTT(s *Subtitle) []byte {
+	var out strings.Builder
+	out.WriteString("WEBVTT\n\n")
+	for _, cue := range s.Cues {
+		fmt.Fprintf(&out, "%s --> %s%s\n", formatTimestamp(cue.Start, "."), formatTimestamp(cue.End, "."), vttSettings(cue.Position))
+		for _, line := range cue.Lines {
+			out.WriteString(vttText(line))
+			out.WriteByte('\n')
+		}
+		out.WriteByte('\n')
+	}
+	return []byte(out.String())
+}
+
+// vttText escapes the text of a line for WebVTT, keeping its <i>, <b> and <u> tags
+func vttText(line string) string {
+	line = stripTags(line)
+
+	var out strings.Builder
+	last := 0
+	for _, tag := range styleTagPattern.FindAllStringIndex(line, -1) {
+		out.WriteString(vttEscaper.Replace(line[last:tag[0]]))
+		out.WriteString(line[tag[0]:tag[1]])
+		last = tag[1]
+	}
+	out.WriteString(vttEscaper.Replace(line[last:]))
+	return out.String()
+}
+
+// vttSettings returns the cue settings placing a cue at an alignment
+func vttSettings(position Alignment) string {
+	if position < 1 || position > 9 {
+		return ""
+	}
+
+	var settings string
+	switch (position - 1) / 3 {
+	case 1:
+		settings += " line:50%"
+	case 2:
+		settings += " line:0"
+	}
+	switch (position - 1) % 3 {
+	case 0:
+		settings += " align:left"
+	case 2:
+		settings += " align:right"
+	}
+	return settings
+}
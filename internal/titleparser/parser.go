@@ -0,0 +1,185 @@
+package titleparser
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Metadata is the structured information found in a subtitle post title
+type Metadata struct {
+	// Name is the title with boilerplate, year, episode markers, release tags and credits removed
+	Name       string
+	Year       int
+	Season     int
+	Episode    int
+	Tags       []string
+	Translator string
+}
+
+var (
+	boilerplatePattern = regexp.MustCompile(`(?i)(with\s+)?sinhala\s+sub(title)?s?|සිංහල\s+උපසිරැ?සි(\s+(සමඟ|සහිතව))?`)
+
+	// Years in brackets are preferred over bare numbers, "2012 (2009)" is a film called 2012
+	bracketYearPattern = regexp.MustCompile(`[(\[]\s*((?:19|20)\d{2})\s*[)\]]`)
+	yearPattern        = regexp.MustCompile(`\b((?:19|20)\d{2})\b`)
+
+	seasonEpisodePattern = regexp.MustCompile(`(?i)\bS(\d{1,2})\s*E(\d{1,3})\b`)
+	crossEpisodePattern  = regexp.MustCompile(`(?i)\b(\d{1,2})x(\d{2,3})\b`)
+	seasonPattern        = regexp.MustCompile(`(?i)(?:\bseason|සීසන්)\s*(\d{1,2})\b|\bS(\d{1,2})\b`)
+	episodePattern       = regexp.MustCompile(`(?i)\b(?:episode|ep)\s*(\d{1,3})\b|\bE(\d{1,3})\b|(?:කථාංගය|කථාංග|එපිසෝඩ්)\s*(\d{1,3})`)
+
+	translatorPattern = regexp.MustCompile(`(?i)(?:translated|subtitled|subbed)\s+by\s*:?\s*([^|\[\]()\-–—,]+)|පරිවර්තනය\s*[:\-]?\s*([^|\[\]()\-–—,]+)`)
+
+	separatorPattern = regexp.MustCompile(`[\s|\-–—:,\[(]+$|^[\s|\-–—:,\])]+|\[\s*\]|\(\s*\)`)
+)
+
+// now is the clock that bounds the years a title can name. Tests pin it so parsing
+// does not change with the calendar.
+var now = time.Now
+
+// releaseTags maps the common spellings of release tags to their canonical form
+var releaseTags = []struct {
+	pattern *regexp.Regexp
+	tag     string
+}{
+	{regexp.MustCompile(`(?i)\bblu-?ray\b`), "BluRay"},
+	{regexp.MustCompile(`(?i)\bbr-?rip\b`), "BRRip"},
+	{regexp.MustCompile(`(?i)\bbd-?rip\b`), "BDRip"},
+	{regexp.MustCompile(`(?i)\bweb-?dl\b`), "WEB-DL"},
+	{regexp.MustCompile(`(?i)\bweb-?rip\b`), "WEBRip"},
+	{regexp.MustCompile(`(?i)\bhd-?rip\b`), "HDRip"},
+	{regexp.MustCompile(`(?i)\bdvd-?rip\b`), "DVDRip"},
+	{regexp.MustCompile(`(?i)\bhdtv\b`), "HDTV"},
+	{regexp.MustCompile(`(?i)\bhd-?cam\b`), "HDCAM"},
+	{regexp.MustCompile(`(?i)\bhd-?ts\b`), "HDTS"},
+	{regexp.MustCompile(`(?i)\b(?:2160p|4k)\b`), "2160p"},
+	{regexp.MustCompile(`(?i)\b1080p\b`), "1080p"},
+	{regexp.MustCompile(`(?i)\b720p\b`), "720p"},
+	{regexp.MustCompile(`(?i)\b480p\b`), "480p"},
+	{regexp.MustCompile(`(?i)\bx264\b`), "x264"},
+	{regexp.MustCompile(`(?i)\b(?:x265|hevc)\b`), "x265"},
+}
+
+// Parse extracts year, season and episode numbers, release tags and the translator from title
+func Parse(title string) Metadata {
+	var meta Metadata
+	rest := StripBoilerplate(title)
+
+	if matches := translatorPattern.FindStringSubmatchIndex(rest); matches != nil {
+		translator, tail := splitTranslator(firstGroup(rest, matches))
+		meta.Translator = translator
+		rest = rest[:matches[0]] + " " + tail + " " + rest[matches[1]:]
+	}
+
+	if matches := seasonEpisodePattern.FindStringSubmatchIndex(rest); matches != nil {
+		meta.Season = atoi(rest[matches[2]:matches[3]])
+		meta.Episode = atoi(rest[matches[4]:matches[5]])
+		rest = cut(rest, matches)
+	} else if matches := crossEpisodePattern.FindStringSubmatchIndex(rest); matches != nil {
+		meta.Season = atoi(rest[matches[2]:matches[3]])
+		meta.Episode = atoi(rest[matches[4]:matches[5]])
+		rest = cut(rest, matches)
+	} else {
+		if matches := seasonPattern.FindStringSubmatchIndex(rest); matches != nil {
+			meta.Season = atoi(firstGroup(rest, matches))
+			rest = cut(rest, matches)
+		}
+		if matches := episodePattern.FindStringSubmatchIndex(rest); matches != nil {
+			meta.Episode = atoi(firstGroup(rest, matches))
+			rest = cut(rest, matches)
+		}
+	}
+
+	for _, release := range releaseTags {
+		if release.pattern.MatchString(rest) {
+			meta.Tags = append(meta.Tags, release.tag)
+			rest = release.pattern.ReplaceAllString(rest, " ")
+		}
+	}
+
+	if matches := bracketYearPattern.FindStringSubmatchIndex(rest); matches != nil {
+		meta.Year = atoi(rest[matches[2]:matches[3]])
+		rest = cut(rest, matches)
+	} else if all := yearPattern.FindAllStringSubmatchIndex(rest, -1); len(all) > 0 {
+		// Use the last bare year, so titles that start with a number keep it in the name.
+		// Numbers in the future are part of the name, as in "Blade Runner 2049".
+		matches := all[len(all)-1]
+		year := atoi(rest[matches[2]:matches[3]])
+		if matches[0] > 0 && year <= now().Year()+1 {
+			meta.Year = year
+			rest = cut(rest, matches)
+		}
+	}
+
+	meta.Name = cleanName(rest)
+	if meta.Name == "" {
+		meta.Name = strings.TrimSpace(title)
+	}
+
+	return meta
+}
+
+// splitTranslator ends a translator name at the first year, episode or release tag after
+// it, returning the name and the rest of the text to parse
+func splitTranslator(text string) (string, string) {
+	words := strings.Fields(text)
+	for i, word := range words {
+		if yearPattern.MatchString(word) || seasonEpisodePattern.MatchString(word) || isReleaseTag(word) {
+			return strings.Join(words[:i], " "), strings.Join(words[i:], " ")
+		}
+	}
+	return strings.Join(words, " "), ""
+}
+
+func isReleaseTag(word string) bool {
+	for _, release := range releaseTags {
+		if release.pattern.MatchString(word) {
+			return true
+		}
+	}
+	return false
+}
+
+// StripBoilerplate removes the "Sinhala Subtitles" style phrases sites add to post titles
+func StripBoilerplate(title string) string {
+	return cleanName(boilerplatePattern.ReplaceAllString(title, " "))
+}
+
+// IsCredit reports whether text starts with a translator credit, such as
+// "Translated by Kasun" or "පරිවර්තනය: Kasun"
+func IsCredit(text string) bool {
+	loc := translatorPattern.FindStringIndex(text)
+	return loc != nil && strings.TrimSpace(text[:loc[0]]) == ""
+}
+
+func cleanName(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	for {
+		cleaned := strings.Join(strings.Fields(separatorPattern.ReplaceAllString(s, " ")), " ")
+		if cleaned == s {
+			return s
+		}
+		s = cleaned
+	}
+}
+
+// firstGroup returns the first non-empty capture group of a match
+func firstGroup(s string, matches []int) string {
+	for i := 2; i+1 < len(matches); i += 2 {
+		if matches[i] >= 0 {
+			return s[matches[i]:matches[i+1]]
+		}
+	}
+	return ""
+}
+
+func cut(s string, matches []int) string {
+	return s[:matches[0]] + " " + s[matches[1]:]
+}
+
+func atoi(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}
@@ -0,0 +1,131 @@
+package titleparser
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// pinClock makes Parse see the given year for the rest of the test
+func pinClock(t *testing.T, year int) {
+	t.Helper()
+	now = func() time.Time { return time.Date(year, time.June, 1, 0, 0, 0, 0, time.UTC) }
+	t.Cleanup(func() { now = time.Now })
+}
+
+func TestParse(t *testing.T) {
+	pinClock(t, 2025)
+
+	tests := []struct {
+		title string
+		want  Metadata
+	}{
+		{
+			"Oppenheimer (2023) Sinhala Subtitles | සිංහල උපසිරැසි සමඟ",
+			Metadata{Name: "Oppenheimer", Year: 2023},
+		},
+		{
+			"Loki S02E03 (2023) WEB-DL 1080p Sinhala Subtitles",
+			Metadata{Name: "Loki", Year: 2023, Season: 2, Episode: 3, Tags: []string{"WEB-DL", "1080p"}},
+		},
+		{
+			"The Office 3x05 HDTV",
+			Metadata{Name: "The Office", Season: 3, Episode: 5, Tags: []string{"HDTV"}},
+		},
+		{
+			"House of the Dragon Season 2 Episode 8",
+			Metadata{Name: "House of the Dragon", Season: 2, Episode: 8},
+		},
+		{
+			"Wednesday සීසන් 1 කථාංගය 4",
+			Metadata{Name: "Wednesday", Season: 1, Episode: 4},
+		},
+		{
+			"Dune: Part Two [2024] BluRay x265 Translated by Kasun Perera",
+			Metadata{Name: "Dune: Part Two", Year: 2024, Tags: []string{"BluRay", "x265"}, Translator: "Kasun Perera"},
+		},
+		{
+			"Inception 2010 Blu-Ray 720p",
+			Metadata{Name: "Inception", Year: 2010, Tags: []string{"BluRay", "720p"}},
+		},
+		{
+			"1917 (2019)",
+			Metadata{Name: "1917", Year: 2019},
+		},
+		{
+			"Blade Runner 2049",
+			Metadata{Name: "Blade Runner 2049"},
+		},
+		{
+			"2012",
+			Metadata{Name: "2012"},
+		},
+		{
+			"Avatar 2026",
+			Metadata{Name: "Avatar", Year: 2026},
+		},
+		{
+			"Space Odyssey 2027",
+			Metadata{Name: "Space Odyssey 2027"},
+		},
+		{
+			"Dune (2021) Translated by Kasun 1080p BluRay",
+			Metadata{Name: "Dune", Year: 2021, Tags: []string{"BluRay", "1080p"}, Translator: "Kasun"},
+		},
+		{
+			"Loki S02E03 Subtitled by Nimal 2023",
+			Metadata{Name: "Loki", Year: 2023, Season: 2, Episode: 3, Translator: "Nimal"},
+		},
+		{
+			"ජීවිතය පරිවර්තනය: නිමල් (2020)",
+			Metadata{Name: "ජීවිතය", Year: 2020, Translator: "නිමල්"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			if got := Parse(tt.title); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse(%q) = %+v, want %+v", tt.title, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStripBoilerplate(t *testing.T) {
+	tests := []struct {
+		title string
+		want  string
+	}{
+		{"Oppenheimer (2023) Sinhala Subtitles", "Oppenheimer (2023)"},
+		{"Oppenheimer (2023) [Sinhala Subtitles]", "Oppenheimer (2023)"},
+		{"The Batman | සිංහල උපසිරැසි සමඟ", "The Batman"},
+		{"The Batman | සිංහල උපසිරසි සමඟ", "The Batman"},
+		{"Avatar with Sinhala Subtitle", "Avatar"},
+	}
+
+	for _, tt := range tests {
+		if got := StripBoilerplate(tt.title); got != tt.want {
+			t.Errorf("StripBoilerplate(%q) = %q, want %q", tt.title, got, tt.want)
+		}
+	}
+}
+
+func TestIsCredit(t *testing.T) {
+	tests := []struct {
+		text string
+		want bool
+	}{
+		{"Translated by Kasun", true},
+		{"  subtitled by: Nimal", true},
+		{"පරිවර්තනය - කසුන්", true},
+		{"It was translated by a friend", false},
+		{"Translated", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		if got := IsCredit(tt.text); got != tt.want {
+			t.Errorf("IsCredit(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}